@@ -7,51 +7,60 @@ import (
 	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
 )
 
-type getPathFunc func(*tmdbapi.Client, string, string) ([]int, error)
+type getPathFunc func(tmdbapi.Source, string, string) (tmdbapi.Path, error)
 
+type newClientFunc func() (*tmdbapi.Client, error)
 
 
-func Benchmark(getPath getPathFunc, token string, iter int) error {
+
+func Benchmark(getPath getPathFunc, newClient newClientFunc, iter int) error {
 	fmt.Println("Running cache benchmark")
 	start := time.Now()
 	total := 0.0
+	stats := tmdbapi.Stats{}
 	for i := 0; i < iter; i++ {
-		avg, err := runBenchCache(getPath, token)
+		avg, s, err := runBenchCache(getPath, newClient)
 		if err != nil { return err }
 		total += avg
+		stats = addStats(stats, s)
 	}
 	dur := time.Since(start)
 	dur /= time.Duration(iter)
 	fmt.Printf("\nFinished with success rate %f, and average time of %s\n",
 		total / float64(iter), dur.String(),
 	)
+	printStats(stats, iter)
 
 	fmt.Println("Running no cache benchmark")
 	start = time.Now()
 	total = 0.0
+	stats = tmdbapi.Stats{}
 	for i := 0; i < iter; i++ {
-		avg, err := runBenchNoCache(getPath, token)
+		avg, s, err := runBenchNoCache(getPath, newClient)
 		if err != nil { return err }
 		total += avg
+		stats = addStats(stats, s)
 	}
 	dur = time.Since(start)
 	dur /= time.Duration(iter)
 	fmt.Printf("\nFinished with success rate %f, and average time of %s\n",
 		total / float64(iter), dur.String(),
 	)
+	printStats(stats, iter)
 	
 	return nil
 }
 
 func Compare(
 	test1, test2 getPathFunc,
-	test1Title, test2Title, token string,
+	test1Title, test2Title string,
+	newClient newClientFunc,
 	iter int, 
 ) (float64, float64, error) {
 	avgLen1, avgLen2 := 0.0, 0.0
 	fmt.Printf("Running cache benchmark for \"%s\"...\n", test1Title)
 	start := time.Now()
-	avg, err := runBenchCache(test1, token)
+	avg, _, err := runBenchCache(test1, newClient)
 	avgLen1 += avg
 	if err != nil { return 0.0, 0.0, err }
 	dur := time.Since(start)
@@ -62,7 +71,7 @@ func Compare(
 
 	fmt.Printf("Running cache benchmark for \"%s\"...\n", test2Title)
 	start = time.Now()
-	avg, err = runBenchCache(test2, token)
+	avg, _, err = runBenchCache(test2, newClient)
 	avgLen2 += avg
 	if err != nil { return 0.0, 0.0, err }
 	dur = time.Since(start)
@@ -73,7 +82,7 @@ func Compare(
 
 	fmt.Printf("Running no cache benchmark for \"%s\"...\n", test1Title)
 	start = time.Now()
-	avg, err = runBenchNoCache(test1, token)
+	avg, _, err = runBenchNoCache(test1, newClient)
 	avgLen1 += avg
 	if err != nil { return 0.0, 0.0, err }
 	dur = time.Since(start)
@@ -84,7 +93,7 @@ func Compare(
 
 	fmt.Printf("Running no cache benchmark for \"%s\"...\n", test2Title)
 	start = time.Now()
-	avg, err = runBenchNoCache(test2, token)
+	avg, _, err = runBenchNoCache(test2, newClient)
 	avgLen2 += avg
 	if err != nil { return 0.0, 0.0, err }
 	dur = time.Since(start)
@@ -98,8 +107,8 @@ func Compare(
 
 func runBenchCache(
 	getPath getPathFunc,
-	token string,
-) (float64, error) {
+	newClient newClientFunc,
+) (float64, tmdbapi.Stats, error) {
 	tests := map[int]struct{
 		src string
 		dest string
@@ -137,19 +146,23 @@ func runBenchCache(
 		},
 	}
 	count := 0
-	client := tmdbapi.New(token, time.Second * 5)
+	client, err := newClient()
+	if err != nil { return 0, tmdbapi.Stats{}, err }
 	for _, test := range tests {
-		out, err := getPath(&client, test.src, test.dest)
+		out, err := getPath(client, test.src, test.dest)
 		fmt.Print(".")
-		if err != nil { return 0, err }
-		if len(out) - 1 == test.expectedLength {
+		if err != nil { return 0, tmdbapi.Stats{}, err }
+		if out.Degree == test.expectedLength {
 			count++
 		}
 	}
-	return float64(count) / 6.0, nil
+	return float64(count) / 6.0, client.Stats(), nil
 }
 
-func runBenchNoCache(getPath getPathFunc, token string) (float64, error) {
+func runBenchNoCache(
+	getPath getPathFunc,
+	newClient newClientFunc,
+) (float64, tmdbapi.Stats, error) {
 	tests := map[int]struct{
 		src string
 		dest string
@@ -187,14 +200,30 @@ func runBenchNoCache(getPath getPathFunc, token string) (float64, error) {
 		},
 	}
 	count := 0
+	stats := tmdbapi.Stats{}
 	for _, test := range tests {
-		client := tmdbapi.New(token, time.Second * 5)
-		out, err := getPath(&client, test.src, test.dest)
+		client, err := newClient()
+		if err != nil { return 0, tmdbapi.Stats{}, err }
+		out, err := getPath(client, test.src, test.dest)
 		fmt.Print(".")
-		if err != nil { return 0, err }
-		if len(out) - 1 == test.expectedLength {
+		if err != nil { return 0, tmdbapi.Stats{}, err }
+		if out.Degree == test.expectedLength {
 			count++
 		}
+		stats = addStats(stats, client.Stats())
+	}
+	return float64(count) / 6.0, stats, nil
+}
+
+func addStats(a, b tmdbapi.Stats) tmdbapi.Stats {
+	return tmdbapi.Stats{
+		APICalls:  a.APICalls + b.APICalls,
+		Coalesced: a.Coalesced + b.Coalesced,
 	}
-	return float64(count) / 6.0, nil
+}
+
+func printStats(stats tmdbapi.Stats, iter int) {
+	fmt.Printf("Made %d API calls per run, coalescing saved %d more\n",
+		stats.APICalls / int64(iter), stats.Coalesced / int64(iter),
+	)
 }
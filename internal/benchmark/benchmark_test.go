@@ -0,0 +1,41 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+	"github.com/BigStinko/mtmsolver/internal/tmdbtest"
+)
+
+func newFakeClientFunc(t *testing.T) newClientFunc {
+	t.Helper()
+	server := tmdbtest.NewServer()
+	t.Cleanup(server.Close)
+
+	return func() (*tmdbapi.Client, error) {
+		client := tmdbapi.New("Bearer test-token", time.Second * 5)
+		client.SetBaseURL(server.BaseURL())
+		return &client, nil
+	}
+}
+
+func TestBenchmarkOffline(t *testing.T) {
+	newClient := newFakeClientFunc(t)
+
+	rate, _, err := runBenchCache(tmdbapi.GetPath, newClient)
+	if err != nil { t.Fatal(err) }
+	if rate != 1.0 {
+		t.Errorf("cache benchmark success rate %f, wanted 1.0", rate)
+	}
+
+	rate, _, err = runBenchNoCache(tmdbapi.GetPath, newClient)
+	if err != nil { t.Fatal(err) }
+	if rate != 1.0 {
+		t.Errorf("no cache benchmark success rate %f, wanted 1.0", rate)
+	}
+
+	if err := Benchmark(tmdbapi.GetPath, newClient, 1); err != nil {
+		t.Error(err)
+	}
+}
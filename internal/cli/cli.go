@@ -0,0 +1,335 @@
+// Package cli implements the mtmsolver command line.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/render"
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+	"github.com/BigStinko/mtmsolver/internal/tmdbcache"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK     = 0
+	ExitError  = 1
+	ExitUsage  = 2
+	ExitNoPath = 3
+	// ExitAmbiguous is returned when a title matches several movies, which
+	// are listed on stderr.
+	ExitAmbiguous = 4
+	// ExitNotFound is returned when a movie or actor doesn't exist on TMDB.
+	ExitNotFound = 5
+	// ExitUnauthorized is returned when TMDB rejects the API token.
+	ExitUnauthorized = 6
+	// ExitUpstream is returned when TMDB keeps failing with server errors or
+	// rate limiting after the retries are spent.
+	ExitUpstream = 7
+)
+
+const defaultTokenEnv = "BEARER_TOKEN"
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, fs *flag.FlagSet, args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"path", "<movieA> <movieB>", "Find the shortest chain of shared actors between two movies.", runPath},
+		{"actor-path", "<actorA> <actorB>", "Find the shortest chain of shared movies between two people.", runActorPath},
+		{"mixed-path", "<from> <to>", "Find the shortest chain between movies or people, given as actor:name.", runMixedPath},
+		{"neighbors", "<movie>", "List the movies that share an actor with a movie.", runNeighbors},
+		{"bench", "", "Run the path benchmark against the API.", runBench},
+		{"cache", "stats|compact|clear <file>", "Inspect or maintain a persistent cache file.", runCache},
+		{"shell", "", "Run queries interactively against one warm client and cache.", runShell},
+		{"serve", "", "Serve path search over an HTTP JSON API.", runServe},
+	}
+}
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run executes the command line in args, without the program name, and
+// returns the process exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		c.usage()
+		return ExitUsage
+	}
+
+	name := args[0]
+	switch name {
+	case "-h", "-help", "--help":
+		c.usage()
+		return ExitOK
+	case "help":
+		if len(args) < 2 {
+			c.usage()
+			return ExitOK
+		}
+		cmd, ok := lookup(args[1])
+		if !ok {
+			fmt.Fprintf(stderr, "unknown command %q\n", args[1])
+			return ExitUsage
+		}
+		return cmd.run(c, c.flagSet(cmd), []string{"-help"})
+	}
+
+	cmd, ok := lookup(name)
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+		c.usage()
+		return ExitUsage
+	}
+	return cmd.run(c, c.flagSet(cmd), args[1:])
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "usage: mtmsolver <command> [flags] [args]")
+	fmt.Fprintln(c.stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(c.stderr, "\nRun \"mtmsolver <command> -help\" for the flags of a command.")
+}
+
+func (c *cli) flagSet(cmd command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: mtmsolver %s [flags] %s\n\n%s\n\nflags:\n",
+			cmd.name, cmd.args, cmd.summary,
+		)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of a command. When it returns false the command
+// should stop with the returned code.
+func (c *cli) parse(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK, false
+	}
+	if err != nil {
+		return ExitUsage, false
+	}
+	return ExitOK, true
+}
+
+func (c *cli) fail(err error) int {
+	fmt.Fprintln(c.stderr, "error:", err)
+	var ambiguous *tmdbapi.AmbiguousTitleError
+	switch {
+	case errors.As(err, &ambiguous):
+		c.listCandidates(ambiguous)
+		return ExitAmbiguous
+	case errors.Is(err, tmdbapi.ErrNoPath):
+		return ExitNoPath
+	case errors.Is(err, tmdbapi.ErrUnauthorized):
+		fmt.Fprintln(c.stderr, "check the token passed with -token, -token-file or -token-env")
+		return ExitUnauthorized
+	case errors.Is(err, tmdbapi.ErrNotFound):
+		return ExitNotFound
+	case errors.Is(err, tmdbapi.ErrServer), errors.Is(err, tmdbapi.ErrRateLimited):
+		return ExitUpstream
+	}
+	return ExitError
+}
+
+func (c *cli) listCandidates(err *tmdbapi.AmbiguousTitleError) {
+	for _, movie := range err.Candidates {
+		fmt.Fprintf(c.stderr, "  %s\n", movieLabel(movie))
+	}
+}
+
+// movieLabel is how a movie can be asked for again, with its year when known.
+func movieLabel(movie tmdbapi.MovieResource) string {
+	if movie.Year() == 0 {
+		return movie.Title
+	}
+	return fmt.Sprintf("%s (%d)", movie.Title, movie.Year())
+}
+
+// withYear adds year to a title query unless it is 0.
+func withYear(title string, year int) string {
+	if year == 0 {
+		return title
+	}
+	return fmt.Sprintf("%s (%d)", title, year)
+}
+
+// stringList is a flag that can be given more than once, keeping every value
+// in order.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (c *cli) usageError(fs *flag.FlagSet, format string, args ...any) int {
+	fmt.Fprintf(c.stderr, format + "\n\n", args...)
+	fs.Usage()
+	return ExitUsage
+}
+
+// options are the flags shared by every command that talks to the API.
+type options struct {
+	searchFactor int
+	maxRoutines  int
+	timeout      time.Duration
+	format       string
+	token        string
+	tokenEnv     string
+	tokenFile    string
+	baseURL      string
+	cachePath    string
+	cacheTTL     time.Duration
+	cacheLimits  tmdbcache.Limits
+	requestRate  float64
+	progress     bool
+	language     string
+	region       string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.IntVar(&o.searchFactor, "searchfactor", 40, "cast members fetched per movie, 0 for all")
+	fs.IntVar(&o.maxRoutines, "maxroutines", 20, "concurrent expansions per search level")
+	fs.DurationVar(&o.timeout, "timeout", 2 * time.Minute, "give up after this long, 0 for no limit")
+	fs.StringVar(&o.format, "format", string(render.Text), "output format: text, json, csv or markdown")
+	fs.StringVar(&o.token, "token", "", "TMDB API read access token")
+	fs.StringVar(&o.tokenEnv, "token-env", defaultTokenEnv, "environment variable holding the token")
+	fs.StringVar(&o.tokenFile, "token-file", "", "file holding the token")
+	fs.StringVar(&o.baseURL, "base-url", "", "TMDB API root, for proxies and testing")
+	fs.StringVar(&o.cachePath, "cache", "", "persistent cache file, kept in memory when empty")
+	fs.DurationVar(&o.cacheTTL, "cache-ttl", 7 * 24 * time.Hour, "age after which cached entries are refetched")
+	fs.Float64Var(&o.requestRate, "rate", 0, "maximum API requests per second, 0 for no limit")
+	fs.BoolVar(&o.progress, "progress", false, "show search progress on stderr")
+	fs.StringVar(&o.language, "language", "en-US", "language titles and names are shown in, as in fr-FR")
+	fs.StringVar(&o.region, "region", "", "country searches are resolved for, as in FR")
+}
+
+// registerCacheLimits adds the flags bounding the cache, for the commands
+// that keep one client alive across queries.
+func (o *options) registerCacheLimits(fs *flag.FlagSet) {
+	fs.IntVar(&o.cacheLimits.MaxEntries, "cache-max-entries", 0, "cached entries kept before the least recently used are evicted, 0 for no limit")
+	fs.IntVar(&o.cacheLimits.MaxBytes, "cache-max-bytes", 0, "rough memory the cache may hold, 0 for no limit")
+}
+
+// bearer returns the authorization header from the first token source set:
+// -token, -token-file, then the -token-env variable.
+func (o *options) bearer() (string, error) {
+	token := o.token
+	if token == "" && o.tokenFile != "" {
+		dat, err := os.ReadFile(o.tokenFile)
+		if err != nil { return "", err }
+		token = string(dat)
+	}
+	if token == "" && o.tokenEnv != "" {
+		token = os.Getenv(o.tokenEnv)
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("no API token: pass -token or -token-file, or set %s", o.tokenEnv)
+	}
+	if !strings.HasPrefix(token, "Bearer ") {
+		token = "Bearer " + token
+	}
+	return token, nil
+}
+
+// newClient builds a client from the options. The returned function closes
+// its cache.
+func (o *options) newClient() (*tmdbapi.Client, func(), error) {
+	client, err := o.newColdClient()
+	if err != nil { return nil, nil, err }
+	closeCache := func() {}
+	if o.cachePath != "" {
+		cache, err := tmdbcache.Open(o.cachePath, o.cacheTTL)
+		if err != nil { return nil, nil, err }
+		client.SetCache(cache)
+		closeCache = func() { cache.Close() }
+	}
+	client.Cache().SetLimits(o.cacheLimits)
+	return client, closeCache, nil
+}
+
+// newColdClient builds a client from the options that starts with an empty
+// in-memory cache, leaving -cache alone.
+func (o *options) newColdClient() (*tmdbapi.Client, error) {
+	header, err := o.bearer()
+	if err != nil { return nil, err }
+
+	client := tmdbapi.New(header, time.Second * 5)
+	client.SetSearchFactor(o.searchFactor)
+	client.SetMaxRoutines(o.maxRoutines)
+	client.SetLanguage(o.language)
+	client.SetRegion(o.region)
+	if o.requestRate > 0 {
+		client.SetRateLimit(o.requestRate)
+	}
+	if o.baseURL != "" {
+		client.SetBaseURL(o.baseURL)
+	}
+	return &client, nil
+}
+
+func (o *options) context() (context.Context, context.CancelFunc) {
+	if o.timeout > 0 {
+		return context.WithTimeout(context.Background(), o.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// searchContext is context with search progress shown on stderr when
+// -progress is set. The returned function also clears the progress line.
+func (c *cli) searchContext(o *options) (context.Context, context.CancelFunc) {
+	ctx, cancel := o.context()
+	if !o.progress {
+		return ctx, cancel
+	}
+	shown := false
+	ctx = tmdbapi.WithProgress(ctx, func(p tmdbapi.Progress) {
+		shown = true
+		fmt.Fprintf(c.stderr, "\r\x1b[Klevel %d (%s) | frontier %d/%d | visited %d | api calls %d",
+			p.Level, p.Side, p.SrcFrontier, p.DestFrontier, p.Visited, p.APICalls,
+		)
+	})
+	return ctx, func() {
+		cancel()
+		if shown {
+			fmt.Fprint(c.stderr, "\r\x1b[K")
+		}
+	}
+}
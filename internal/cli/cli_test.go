@@ -0,0 +1,296 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+	"github.com/BigStinko/mtmsolver/internal/tmdbtest"
+)
+
+func run(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	code := Run(args, strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func serverArgs(t *testing.T) []string {
+	t.Helper()
+	server := tmdbtest.NewServer()
+	t.Cleanup(server.Close)
+	return []string{"-base-url", server.BaseURL(), "-token", "test-token"}
+}
+
+func TestPathCommand(t *testing.T) {
+	args := append([]string{"path", "-format", "json"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "Midsommar", "Gravity")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var path tmdbapi.Path
+	if err := json.Unmarshal([]byte(stdout), &path); err != nil { t.Fatal(err) }
+	if path.Degree != 3 {
+		t.Errorf("got degree %d, wanted 3", path.Degree)
+	}
+}
+
+func TestPathWholeCast(t *testing.T) {
+	args := append([]string{"path", "-searchfactor", "0", "-format", "json"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "Midsommar", "Gravity")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var path tmdbapi.Path
+	if err := json.Unmarshal([]byte(stdout), &path); err != nil { t.Fatal(err) }
+	if path.Degree != 3 {
+		t.Errorf("got degree %d, wanted 3", path.Degree)
+	}
+}
+
+func TestPathCacheKeepsSearchFactors(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "cache.log")
+	args := append([]string{"path", "-cache", cache, "-format", "json"}, serverArgs(t)...)
+	run(t, append(args, "-searchfactor", "1", "Midsommar", "Gravity")...)
+
+	code, stdout, stderr := run(t, append(args, "-searchfactor", "0", "Midsommar", "Gravity")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var path tmdbapi.Path
+	if err := json.Unmarshal([]byte(stdout), &path); err != nil { t.Fatal(err) }
+	if path.Degree != 3 {
+		t.Errorf("got degree %d from casts cached at searchfactor 1, wanted 3", path.Degree)
+	}
+}
+
+func TestPathAll(t *testing.T) {
+	args := append([]string{"path", "-all", "-format", "json"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "Midsommar", "Gravity")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var paths []tmdbapi.Path
+	if err := json.Unmarshal([]byte(stdout), &paths); err != nil { t.Fatal(err) }
+	if len(paths) != 1 || paths[0].Degree != 3 {
+		t.Errorf("got paths %+v", paths)
+	}
+}
+
+func TestPathK(t *testing.T) {
+	args := append([]string{"path", "-k", "3", "-format", "json"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "Midsommar", "Gravity")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var paths []tmdbapi.Path
+	if err := json.Unmarshal([]byte(stdout), &paths); err != nil { t.Fatal(err) }
+	if len(paths) == 0 || len(paths) > 3 || paths[0].Degree != 3 {
+		t.Fatalf("got paths %+v", paths)
+	}
+	for i := 1; i < len(paths); i++ {
+		if paths[i].Degree < paths[i - 1].Degree {
+			t.Errorf("path %d is shorter than the one before it", i)
+		}
+	}
+}
+
+func TestPathConstraints(t *testing.T) {
+	// the fixture's only path from Midsommar to Gravity is through Little
+	// Women and Fantastic Mr. Fox, linked by Meryl Streep
+	tests := []struct{
+		flags []string
+		code  int
+	}{
+		{[]string{"-via", "Little Women (2019)", "-via", "actor:Meryl Streep"}, ExitOK},
+		{[]string{"-max-hops", "3"}, ExitOK},
+		{[]string{"-ban-actor", "Meryl Streep"}, ExitNoPath},
+		{[]string{"-ban-movie", "Little Women (2019)"}, ExitNoPath},
+		{[]string{"-via", "Fantastic Mr. Fox", "-via", "Little Women (2019)"}, ExitNoPath},
+		{[]string{"-max-hops", "2"}, ExitNoPath},
+		{[]string{"-ban-actor", "Nobody At All"}, ExitNotFound},
+		{[]string{"-all", "-max-hops", "3"}, ExitUsage},
+	}
+	for _, test := range tests {
+		args := append([]string{"path", "-format", "json"}, test.flags...)
+		args = append(append(args, serverArgs(t)...), "Midsommar", "Gravity")
+		code, stdout, stderr := run(t, args...)
+		if code != test.code {
+			t.Errorf("%v: exit %d, wanted %d: %s", test.flags, code, test.code, stderr)
+			continue
+		}
+		if code != ExitOK {
+			continue
+		}
+		var path tmdbapi.Path
+		if err := json.Unmarshal([]byte(stdout), &path); err != nil { t.Fatal(err) }
+		if path.Degree != 3 {
+			t.Errorf("%v: got degree %d, wanted 3", test.flags, path.Degree)
+		}
+	}
+}
+
+func TestNoPathExitCode(t *testing.T) {
+	args := append([]string{"actor-path"}, serverArgs(t)...)
+	code, _, stderr := run(t, append(args, "Harvey Keitel", "Michael Caine")...)
+	if code != ExitNoPath {
+		t.Errorf("exit %d, wanted %d: %s", code, ExitNoPath, stderr)
+	}
+}
+
+func TestActorPathCommand(t *testing.T) {
+	args := append([]string{"actor-path", "-format", "csv"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "Florence Pugh", "George Clooney")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 6 || lines[0] != "kind,id,name" || lines[5] != "actor,1461,George Clooney" {
+		t.Errorf("got:\n%s", stdout)
+	}
+}
+
+func TestMixedPathCommand(t *testing.T) {
+	args := append([]string{"mixed-path", "-format", "csv"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "actor:Florence Pugh", "Gravity")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 7 || lines[1] != "actor,1373737,Florence Pugh" || lines[6] != "movie,49047,Gravity" {
+		t.Errorf("got:\n%s", stdout)
+	}
+}
+
+func TestNeighborsCommand(t *testing.T) {
+	args := append([]string{"neighbors"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "Reservoir Dogs")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "Pulp Fiction") || strings.Contains(stdout, "Reservoir Dogs") {
+		t.Errorf("got:\n%s", stdout)
+	}
+}
+
+func TestCacheCommand(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache.log")
+	args := append([]string{"path", "-cache", file}, serverArgs(t)...)
+	if code, _, stderr := run(t, append(args, "Reservoir Dogs", "Pulp Fiction")...); code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+
+	code, stdout, stderr := run(t, "cache", "stats", file)
+	if code != ExitOK || !strings.Contains(stdout, "entries:") {
+		t.Errorf("stats exit %d: %s%s", code, stdout, stderr)
+	}
+	if code, _, stderr := run(t, "cache", "compact", file); code != ExitOK {
+		t.Errorf("compact exit %d: %s", code, stderr)
+	}
+	if code, _, stderr := run(t, "cache", "clear", file); code != ExitOK {
+		t.Errorf("clear exit %d: %s", code, stderr)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("cache file still there after clear")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	tests := []struct{
+		args []string
+		code int
+		output string
+	}{
+		{nil, ExitUsage, "commands:"},
+		{[]string{"--help"}, ExitOK, "commands:"},
+		{[]string{"nope"}, ExitUsage, "unknown command"},
+		{[]string{"path", "--help"}, ExitOK, "-searchfactor"},
+		{[]string{"help", "neighbors"}, ExitOK, "mtmsolver neighbors"},
+		{[]string{"serve", "-help"}, ExitOK, "-addr"},
+		{[]string{"path", "only one"}, ExitUsage, "two movie titles"},
+		{[]string{"path", "-format", "xml", "a", "b"}, ExitUsage, "unknown output format"},
+		{[]string{"path", "-all", "-k", "2", "a", "b"}, ExitUsage, "can't be used together"},
+		{[]string{"cache", "shrink", "file"}, ExitUsage, "unknown cache action"},
+	}
+	for _, test := range tests {
+		code, _, stderr := run(t, test.args...)
+		if code != test.code || !strings.Contains(stderr, test.output) {
+			t.Errorf("%v: exit %d, wanted %d with %q in:\n%s",
+				test.args, code, test.code, test.output, stderr,
+			)
+		}
+	}
+}
+
+func TestMissingToken(t *testing.T) {
+	t.Setenv("MTMSOLVER_TEST_TOKEN", "")
+	code, _, stderr := run(t, "path", "-token-env", "MTMSOLVER_TEST_TOKEN", "a", "b")
+	if code != ExitError || !strings.Contains(stderr, "no API token") {
+		t.Errorf("exit %d: %s", code, stderr)
+	}
+}
+
+func TestTMDBErrorsExitDistinctly(t *testing.T) {
+	server := tmdbtest.NewServer()
+	defer server.Close()
+	server.SetToken("test-token")
+	base := []string{"path", "-base-url", server.BaseURL()}
+
+	code, _, stderr := run(t, append(base, "-token", "wrong", "Midsommar", "Gravity")...)
+	if code != ExitUnauthorized || !strings.Contains(stderr, "Invalid API key") {
+		t.Errorf("bad token: exit %d: %s", code, stderr)
+	}
+	if strings.Contains(stderr, "Could not find") {
+		t.Errorf("bad token reported as a missing movie: %s", stderr)
+	}
+
+	code, _, stderr = run(t, append(base, "-token", "test-token", "tmdb:1", "Gravity")...)
+	if code != ExitNotFound {
+		t.Errorf("unknown id: exit %d: %s", code, stderr)
+	}
+}
+
+func TestBenchHonorsTimeout(t *testing.T) {
+	args := append([]string{"bench", "-timeout", "1ns"}, serverArgs(t)...)
+	code, _, stderr := run(t, args...)
+	if code != ExitError || !strings.Contains(stderr, "deadline exceeded") {
+		t.Errorf("exit %d, wanted the timeout to stop the benchmark: %s", code, stderr)
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	args := append([]string{"path", "-progress"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "Kickboxer", "Dirty Rotten Scoundrels")...)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "level 1 (src)") || !strings.HasSuffix(stderr, "\r\x1b[K") {
+		t.Errorf("got progress %q", stderr)
+	}
+	if strings.Contains(stdout, "level") {
+		t.Errorf("progress written to stdout")
+	}
+}
+
+func TestAmbiguousTitle(t *testing.T) {
+	args := append([]string{"path"}, serverArgs(t)...)
+	code, _, stderr := run(t, append(args, "Little Women", "Gravity")...)
+	if code != ExitAmbiguous {
+		t.Errorf("exit %d, wanted %d", code, ExitAmbiguous)
+	}
+	for _, want := range []string{"Little Women (2019)", "Little Women (1994)"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("%q not listed in:\n%s", want, stderr)
+		}
+	}
+
+	args = append([]string{"path", "-from-year", "1994"}, serverArgs(t)...)
+	code, stdout, stderr := run(t, append(args, "Little Women", "Empire of the Sun")...)
+	if code != ExitOK || !strings.Contains(stdout, "Winona Ryder") {
+		t.Errorf("exit %d: %s%s", code, stdout, stderr)
+	}
+}
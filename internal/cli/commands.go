@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/BigStinko/mtmsolver/internal/benchmark"
+	"github.com/BigStinko/mtmsolver/internal/render"
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+	"github.com/BigStinko/mtmsolver/internal/tmdbcache"
+)
+
+func runPath(c *cli, fs *flag.FlagSet, args []string) int {
+	opts := options{}
+	opts.register(fs)
+	fromYear := fs.Int("from-year", 0, "release year of the first movie")
+	toYear := fs.Int("to-year", 0, "release year of the second movie")
+	all := fs.Bool("all", false, "show every shortest path instead of one")
+	limit := fs.Int("limit", 0, "with -all, show at most this many paths, 0 for no limit")
+	k := fs.Int("k", 0, "show the k shortest loopless paths, longer ones included")
+	maxDepth := fs.Int("max-depth", 0, "with -k, leave out paths of more hops, 0 for no limit")
+	rules := pathRules{}
+	fs.Var(&rules.banActors, "ban-actor", "keep an actor out of the path, can be repeated")
+	fs.Var(&rules.banMovies, "ban-movie", "keep a movie out of the path, can be repeated")
+	fs.Var(&rules.via, "via", "pass through a movie, or actor:name, can be repeated in order")
+	fs.IntVar(&rules.maxHops, "max-hops", 0, "leave out paths of more hops, 0 for no limit")
+	if code, ok := c.parse(fs, args); !ok { return code }
+	if fs.NArg() != 2 {
+		return c.usageError(fs, "path takes two movie titles")
+	}
+	if *all && *k > 0 {
+		return c.usageError(fs, "-all and -k can't be used together")
+	}
+	if !rules.empty() && (*all || *k > 0) {
+		return c.usageError(fs, "-ban-actor, -ban-movie, -via and -max-hops can't be used with -all or -k")
+	}
+	format, err := render.ParseFormat(opts.format)
+	if err != nil { return c.usageError(fs, "%s", err) }
+
+	client, closeCache, err := opts.newClient()
+	if err != nil { return c.fail(err) }
+	defer closeCache()
+	ctx, cancel := c.searchContext(&opts)
+	defer cancel()
+
+	from, to := withYear(fs.Arg(0), *fromYear), withYear(fs.Arg(1), *toYear)
+	switch {
+	case *all:
+		err = writeAllPaths(ctx, c.stdout, client, format, from, to, *limit)
+	case *k > 0:
+		err = writeKPaths(ctx, c.stdout, client, format, from, to, *k, *maxDepth)
+	case !rules.empty():
+		err = writeConstrainedPath(ctx, c.stdout, client, format, from, to, rules)
+	default:
+		err = writePath(ctx, c.stdout, client, format, from, to)
+	}
+	if err != nil { return c.fail(err) }
+	return ExitOK
+}
+
+func runActorPath(c *cli, fs *flag.FlagSet, args []string) int {
+	opts := options{}
+	opts.register(fs)
+	if code, ok := c.parse(fs, args); !ok { return code }
+	if fs.NArg() != 2 {
+		return c.usageError(fs, "actor-path takes two names")
+	}
+	format, err := render.ParseFormat(opts.format)
+	if err != nil { return c.usageError(fs, "%s", err) }
+
+	client, closeCache, err := opts.newClient()
+	if err != nil { return c.fail(err) }
+	defer closeCache()
+	ctx, cancel := c.searchContext(&opts)
+	defer cancel()
+
+	if err := writeActorPath(ctx, c.stdout, client, format, fs.Arg(0), fs.Arg(1)); err != nil {
+		return c.fail(err)
+	}
+	return ExitOK
+}
+
+func runMixedPath(c *cli, fs *flag.FlagSet, args []string) int {
+	opts := options{}
+	opts.register(fs)
+	if code, ok := c.parse(fs, args); !ok { return code }
+	if fs.NArg() != 2 {
+		return c.usageError(fs, "mixed-path takes two movie titles or actor:names")
+	}
+	format, err := render.ParseFormat(opts.format)
+	if err != nil { return c.usageError(fs, "%s", err) }
+
+	client, closeCache, err := opts.newClient()
+	if err != nil { return c.fail(err) }
+	defer closeCache()
+	ctx, cancel := c.searchContext(&opts)
+	defer cancel()
+
+	if err := writeMixedPath(ctx, c.stdout, client, format, fs.Arg(0), fs.Arg(1)); err != nil {
+		return c.fail(err)
+	}
+	return ExitOK
+}
+
+func runNeighbors(c *cli, fs *flag.FlagSet, args []string) int {
+	opts := options{}
+	opts.register(fs)
+	year := fs.Int("year", 0, "release year of the movie")
+	if code, ok := c.parse(fs, args); !ok { return code }
+	if fs.NArg() != 1 {
+		return c.usageError(fs, "neighbors takes one movie title")
+	}
+	format, err := render.ParseFormat(opts.format)
+	if err != nil { return c.usageError(fs, "%s", err) }
+
+	client, closeCache, err := opts.newClient()
+	if err != nil { return c.fail(err) }
+	defer closeCache()
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	if err := writeNeighbors(ctx, c.stdout, client, format, withYear(fs.Arg(0), *year)); err != nil {
+		return c.fail(err)
+	}
+	return ExitOK
+}
+
+func runBench(c *cli, fs *flag.FlagSet, args []string) int {
+	opts := options{}
+	opts.register(fs)
+	iter := fs.Int("iter", 1, "benchmark iterations")
+	cassette := fs.String("cassette", "", "directory of recorded API responses")
+	record := fs.Bool("record", false, "record responses missing from -cassette instead of failing")
+	if code, ok := c.parse(fs, args); !ok { return code }
+	if fs.NArg() != 0 {
+		return c.usageError(fs, "bench takes no arguments")
+	}
+	if *iter < 1 {
+		return c.usageError(fs, "-iter must be at least 1")
+	}
+	if _, err := opts.bearer(); err != nil { return c.fail(err) }
+
+	// every run starts from a cold client, so -cache is never opened
+	newClient := func() (*tmdbapi.Client, error) {
+		client, err := opts.newColdClient()
+		if err != nil { return nil, err }
+		if *cassette != "" {
+			mode := tmdbapi.CassetteReplay
+			if *record {
+				mode = tmdbapi.CassetteRecord
+			}
+			client.SetCassette(*cassette, mode)
+		}
+		return client, nil
+	}
+	// -timeout bounds the whole benchmark
+	ctx, cancel := opts.context()
+	defer cancel()
+	getPath := func(s tmdbapi.Source, src, dest string) (tmdbapi.Path, error) {
+		return tmdbapi.GetPathContext(ctx, s, src, dest)
+	}
+	if err := benchmark.Benchmark(getPath, newClient, *iter); err != nil {
+		return c.fail(err)
+	}
+	return ExitOK
+}
+
+func runCache(c *cli, fs *flag.FlagSet, args []string) int {
+	ttl := fs.Duration("cache-ttl", 0, "drop entries older than this when compacting, 0 keeps all")
+	if code, ok := c.parse(fs, args); !ok { return code }
+	if fs.NArg() != 2 {
+		return c.usageError(fs, "cache takes an action and a cache file")
+	}
+	action, path := fs.Arg(0), fs.Arg(1)
+
+	switch action {
+	case "stats":
+		if _, err := os.Stat(path); err != nil { return c.fail(err) }
+		cache, err := tmdbcache.Open(path, *ttl)
+		if err != nil { return c.fail(err) }
+		defer cache.Close()
+		info, err := os.Stat(path)
+		if err != nil { return c.fail(err) }
+		stats := cache.Stats()
+		fmt.Fprintf(c.stdout, "entries: %d\nmemory: %d bytes\nfile: %d bytes\n",
+			stats.Entries, stats.Bytes, info.Size(),
+		)
+	case "compact":
+		if _, err := os.Stat(path); err != nil { return c.fail(err) }
+		cache, err := tmdbcache.Open(path, *ttl)
+		if err != nil { return c.fail(err) }
+		defer cache.Close()
+		if err := cache.Compact(); err != nil { return c.fail(err) }
+	case "clear":
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return c.fail(err)
+		}
+	default:
+		return c.usageError(fs, "unknown cache action %q", action)
+	}
+	return ExitOK
+}
@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/BigStinko/mtmsolver/internal/render"
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+)
+
+// The queries below are shared by the commands and the shell.
+
+func writePath(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	src, dest string,
+) error {
+	path, err := tmdbapi.GetPathContext(ctx, client, src, dest)
+	if err != nil { return err }
+	return render.Path(w, format, path)
+}
+
+func writeAllPaths(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	src, dest string,
+	limit int,
+) error {
+	paths, err := tmdbapi.AllShortestPathsContext(ctx, client, src, dest, limit)
+	if err != nil { return err }
+	return render.Paths(w, format, paths)
+}
+
+func writeKPaths(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	src, dest string,
+	k, maxDepth int,
+) error {
+	paths, err := tmdbapi.KShortestPathsContext(ctx, client, src, dest, k, maxDepth)
+	if err != nil { return err }
+	return render.Paths(w, format, paths)
+}
+
+// pathRules are the path command's constraint flags, naming actors and movies
+// as they are searched for.
+type pathRules struct {
+	banActors stringList
+	banMovies stringList
+	via       stringList
+	maxHops   int
+}
+
+func (r pathRules) empty() bool {
+	return len(r.banActors) == 0 && len(r.banMovies) == 0 && len(r.via) == 0 && r.maxHops == 0
+}
+
+// constraints looks up the actors and movies the rules name.
+func (r pathRules) constraints(
+	ctx context.Context,
+	client *tmdbapi.Client,
+) (tmdbapi.Constraints, error) {
+	c := tmdbapi.Constraints{MaxHops: r.maxHops}
+	for _, name := range r.banActors {
+		node, err := tmdbapi.ResolveEndpoint(ctx, client, tmdbapi.ActorEndpoint(name))
+		if err != nil { return c, err }
+		c.BannedActors = append(c.BannedActors, node.Id)
+	}
+	for _, title := range r.banMovies {
+		node, err := tmdbapi.ResolveEndpoint(ctx, client, tmdbapi.MovieEndpoint(title))
+		if err != nil { return c, err }
+		c.BannedMovies = append(c.BannedMovies, node.Id)
+	}
+	for _, stop := range r.via {
+		node, err := tmdbapi.ResolveEndpoint(ctx, client, tmdbapi.ParseEndpoint(stop))
+		if err != nil { return c, err }
+		c.Via = append(c.Via, node)
+	}
+	return c, nil
+}
+
+func writeConstrainedPath(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	src, dest string,
+	rules pathRules,
+) error {
+	c, err := rules.constraints(ctx, client)
+	if err != nil { return err }
+	path, err := tmdbapi.GetPathConstrainedContext(ctx, client, src, dest, c)
+	if err != nil { return err }
+	return render.Path(w, format, path)
+}
+
+func writeActorPath(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	src, dest string,
+) error {
+	path, err := tmdbapi.GetActorPathContext(ctx, client, src, dest)
+	if err != nil { return err }
+	return writeNodes(ctx, w, client, format, tmdbapi.ActorPathNodes(path))
+}
+
+// writeMixedPath searches between two movies or people, each given as a title
+// or as actor:name.
+func writeMixedPath(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	src, dest string,
+) error {
+	nodes, err := tmdbapi.GetMixedPathContext(
+		ctx, client, tmdbapi.ParseEndpoint(src), tmdbapi.ParseEndpoint(dest),
+	)
+	if err != nil { return err }
+	return writeNodes(ctx, w, client, format, nodes)
+}
+
+// writeNodes writes a path of movies and actors as text, or as a table of
+// their kinds, ids and names.
+func writeNodes(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	nodes []tmdbapi.Node,
+) error {
+	if format == render.Text {
+		return tmdbapi.FprintPath(w, client, nodes)
+	}
+
+	rows := make([][]string, len(nodes))
+	for i, node := range nodes {
+		name, err := tmdbapi.NodeName(ctx, client, node)
+		if err != nil { return err }
+		rows[i] = []string{node.Kind.String(), strconv.Itoa(node.Id), name}
+	}
+	return render.Table(w, format, []string{"kind", "id", "name"}, rows)
+}
+
+func writeNeighbors(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	title string,
+) error {
+	movie, err := findMovie(ctx, client, title)
+	if err != nil { return err }
+	neighbors, err := client.GetNeighborsContext(ctx, movie.Id)
+	if err != nil { return err }
+
+	// the filmographies were fetched for the neighbors, so they name them
+	// without another request per movie
+	titles := make(map[int]string, len(neighbors))
+	cast, err := client.GetCastContext(ctx, movie.Id)
+	if err != nil { return err }
+	for actor := range cast {
+		films, err := client.GetFilmographyContext(ctx, actor)
+		if err != nil { return err }
+		for id, film := range films {
+			titles[id] = film.Name
+		}
+	}
+
+	ids := make([]int, 0, len(neighbors))
+	for id := range neighbors {
+		if id != movie.Id {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	rows := make([][]string, len(ids))
+	for i, id := range ids {
+		rows[i] = []string{strconv.Itoa(id), titles[id]}
+	}
+	return render.Table(w, format, []string{"id", "title"}, rows)
+}
+
+// writeCast lists the actors of a movie with the characters they played.
+func writeCast(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	title string,
+) error {
+	movie, err := findMovie(ctx, client, title)
+	if err != nil { return err }
+	cast, err := client.GetCastContext(ctx, movie.Id)
+	if err != nil { return err }
+	return render.Table(w, format, []string{"id", "name", "character"}, roleRows(cast))
+}
+
+// writeFilmography lists the movies of an actor with the characters they
+// played.
+func writeFilmography(
+	ctx context.Context,
+	w io.Writer,
+	client *tmdbapi.Client,
+	format render.Format,
+	name string,
+) error {
+	actor, err := client.GetActorFromNameContext(ctx, name)
+	if err != nil { return err }
+	if actor == tmdbapi.NoName {
+		return &tmdbapi.NotFoundError{Kind: tmdbapi.ActorKind, Query: name}
+	}
+	films, err := client.GetFilmographyContext(ctx, actor.Id)
+	if err != nil { return err }
+	return render.Table(w, format, []string{"id", "title", "character"}, roleRows(films))
+}
+
+func findMovie(
+	ctx context.Context,
+	client *tmdbapi.Client,
+	title string,
+) (tmdbapi.MovieResource, error) {
+	movie, err := client.GetMovieFromTitleContext(ctx, title)
+	if err != nil { return movie, err }
+	if movie == tmdbapi.NoTitle {
+		return movie, &tmdbapi.NotFoundError{Kind: tmdbapi.MovieKind, Query: title}
+	}
+	return movie, nil
+}
+
+func roleRows(roles map[int]tmdbapi.Role) [][]string {
+	ids := make([]int, 0, len(roles))
+	for id := range roles {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	rows := make([][]string, len(ids))
+	for i, id := range ids {
+		rows[i] = []string{strconv.Itoa(id), roles[id].Name, roles[id].Character}
+	}
+	return rows
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/server"
+)
+
+func runServe(c *cli, fs *flag.FlagSet, args []string) int {
+	opts := options{}
+	opts.register(fs)
+	opts.registerCacheLimits(fs)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	if code, ok := c.parse(fs, args); !ok { return code }
+	if fs.NArg() != 0 {
+		return c.usageError(fs, "serve takes no arguments")
+	}
+
+	client, closeCache, err := opts.newClient()
+	if err != nil { return c.fail(err) }
+	defer closeCache()
+
+	handler := server.New(client)
+	// -timeout bounds each request here rather than the whole command
+	handler.SetTimeout(opts.timeout)
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+	fmt.Fprintf(c.stderr, "listening on %s\n", *addr)
+
+	select {
+	case err := <-errs:
+		return c.fail(err)
+	case <-ctx.Done():
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdown); err != nil { return c.fail(err) }
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) { return c.fail(err) }
+	return ExitOK
+}
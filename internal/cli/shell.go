@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/lineedit"
+	"github.com/BigStinko/mtmsolver/internal/render"
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+	"github.com/BigStinko/mtmsolver/internal/tmdbcache"
+)
+
+const shellHelp = `commands:
+  path <movieA> <movieB>        shortest chain of shared actors
+  actor-path <actorA> <actorB>  shortest chain of shared movies
+  mixed-path <from> <to>        shortest chain between movies or actor:names
+  actors <movie>                cast of a movie
+  films <actor>                 filmography of an actor
+  neighbors <movie>             movies sharing an actor with a movie
+  set [<setting> <value>]       show or change searchfactor, maxroutines,
+                                format, timeout or progress (on/off)
+  stats                         API calls and cache usage so far
+  clear-cache                   drop everything cached
+  help                          show this
+  exit                          leave the shell
+Quote titles and names with spaces: path "Reservoir Dogs" "Fight Club"
+Add a year to pick between movies of the same title: actors "Dune (2021)"
+Ctrl-C cancels a running query.
+`
+
+// shell keeps one client, and so one cache, alive across queries.
+type shell struct {
+	*cli
+	opts       *options
+	format     render.Format
+	client     *tmdbapi.Client
+	closeCache func()
+}
+
+func runShell(c *cli, fs *flag.FlagSet, args []string) int {
+	opts := options{}
+	opts.register(fs)
+	opts.registerCacheLimits(fs)
+	history := fs.String("history", defaultHistoryPath(), "file the shell history is kept in, empty for none")
+	if code, ok := c.parse(fs, args); !ok { return code }
+	if fs.NArg() != 0 {
+		return c.usageError(fs, "shell takes no arguments")
+	}
+	format, err := render.ParseFormat(opts.format)
+	if err != nil { return c.usageError(fs, "%s", err) }
+
+	client, closeCache, err := opts.newClient()
+	if err != nil { return c.fail(err) }
+	sh := &shell{cli: c, opts: &opts, format: format, client: client, closeCache: closeCache}
+	defer func() { sh.closeCache() }()
+
+	editor := lineedit.New(c.stdin, c.stdout)
+	defer editor.Close()
+	if *history != "" {
+		if err := editor.LoadHistory(*history); err != nil {
+			fmt.Fprintln(c.stderr, "history:", err)
+		}
+	}
+
+	for {
+		line, err := editor.ReadLine("mtm> ")
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			continue
+		}
+		if err == io.EOF {
+			return ExitOK
+		}
+		if err != nil { return c.fail(err) }
+		editor.AddHistory(line)
+
+		words, err := splitWords(line)
+		if err != nil {
+			fmt.Fprintln(c.stderr, "error:", err)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if words[0] == "exit" || words[0] == "quit" {
+			return ExitOK
+		}
+		if err := sh.exec(words[0], words[1:]); err != nil {
+			fmt.Fprintln(c.stderr, "error:", err)
+			var ambiguous *tmdbapi.AmbiguousTitleError
+			if errors.As(err, &ambiguous) {
+				c.listCandidates(ambiguous)
+			}
+		}
+	}
+}
+
+func (sh *shell) exec(name string, args []string) error {
+	switch name {
+	case "help":
+		fmt.Fprint(sh.stdout, shellHelp)
+	case "path":
+		if len(args) != 2 {
+			return errors.New("path takes two movie titles")
+		}
+		return sh.query(func(ctx context.Context) error {
+			return writePath(ctx, sh.stdout, sh.client, sh.format, args[0], args[1])
+		})
+	case "actor-path":
+		if len(args) != 2 {
+			return errors.New("actor-path takes two names")
+		}
+		return sh.query(func(ctx context.Context) error {
+			return writeActorPath(ctx, sh.stdout, sh.client, sh.format, args[0], args[1])
+		})
+	case "mixed-path":
+		if len(args) != 2 {
+			return errors.New("mixed-path takes two movie titles or actor:names")
+		}
+		return sh.query(func(ctx context.Context) error {
+			return writeMixedPath(ctx, sh.stdout, sh.client, sh.format, args[0], args[1])
+		})
+	case "actors":
+		if len(args) != 1 {
+			return errors.New("actors takes one movie title")
+		}
+		return sh.query(func(ctx context.Context) error {
+			return writeCast(ctx, sh.stdout, sh.client, sh.format, args[0])
+		})
+	case "films":
+		if len(args) != 1 {
+			return errors.New("films takes one name")
+		}
+		return sh.query(func(ctx context.Context) error {
+			return writeFilmography(ctx, sh.stdout, sh.client, sh.format, args[0])
+		})
+	case "neighbors":
+		if len(args) != 1 {
+			return errors.New("neighbors takes one movie title")
+		}
+		return sh.query(func(ctx context.Context) error {
+			return writeNeighbors(ctx, sh.stdout, sh.client, sh.format, args[0])
+		})
+	case "set":
+		return sh.set(args)
+	case "stats":
+		sh.stats()
+	case "clear-cache":
+		return sh.clearCache()
+	default:
+		return fmt.Errorf("unknown command %q, try help", name)
+	}
+	return nil
+}
+
+// query runs fn under the configured timeout, cancelling it on Ctrl-C
+// instead of leaving the shell.
+func (sh *shell) query(fn func(ctx context.Context) error) error {
+	ctx, cancel := sh.searchContext(sh.opts)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	return fn(ctx)
+}
+
+func (sh *shell) set(args []string) error {
+	if len(args) == 0 {
+		progress := "off"
+		if sh.opts.progress {
+			progress = "on"
+		}
+		fmt.Fprintf(sh.stdout, "searchfactor %d\nmaxroutines %d\nformat %s\ntimeout %s\nprogress %s\n",
+			sh.opts.searchFactor, sh.opts.maxRoutines, sh.format, sh.opts.timeout, progress,
+		)
+		return nil
+	}
+	if len(args) != 2 {
+		return errors.New("set takes a setting and a value")
+	}
+
+	switch args[0] {
+	case "searchfactor":
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("searchfactor must be a whole number, got %q", args[1])
+		}
+		// casts already cached were cut to the old limit
+		if n != sh.opts.searchFactor {
+			sh.client.Cache().DropCasts()
+		}
+		sh.opts.searchFactor = n
+		sh.client.SetSearchFactor(n)
+	case "maxroutines":
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("maxroutines must be at least 1, got %q", args[1])
+		}
+		sh.opts.maxRoutines = n
+		sh.client.SetMaxRoutines(n)
+	case "format":
+		format, err := render.ParseFormat(args[1])
+		if err != nil { return err }
+		sh.format = format
+	case "timeout":
+		d, err := time.ParseDuration(args[1])
+		if err != nil { return err }
+		sh.opts.timeout = d
+	case "progress":
+		switch args[1] {
+		case "on":
+			sh.opts.progress = true
+		case "off":
+			sh.opts.progress = false
+		default:
+			return fmt.Errorf("progress is on or off, got %q", args[1])
+		}
+	default:
+		return fmt.Errorf("unknown setting %q", args[0])
+	}
+	return nil
+}
+
+func (sh *shell) stats() {
+	stats := sh.client.Stats()
+	cache := sh.client.Cache().Stats()
+	fmt.Fprintf(sh.stdout,
+		"api calls: %d\ncoalesced: %d\ncache entries: %d\ncache bytes: %d\ncache hits: %d\ncache misses: %d\n",
+		stats.APICalls, stats.Coalesced,
+		cache.Entries, cache.Bytes, cache.Hits, cache.Misses,
+	)
+}
+
+// clearCache drops every cached entry, removing the cache file too when the
+// shell was started with one.
+func (sh *shell) clearCache() error {
+	sh.closeCache()
+	sh.closeCache = func() {}
+	if sh.opts.cachePath == "" {
+		sh.client.SetCache(tmdbcache.NewBounded(sh.opts.cacheLimits))
+		return nil
+	}
+
+	if err := os.Remove(sh.opts.cachePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	cache, err := tmdbcache.Open(sh.opts.cachePath, sh.opts.cacheTTL)
+	if err != nil {
+		sh.client.SetCache(tmdbcache.NewBounded(sh.opts.cacheLimits))
+		return err
+	}
+	cache.SetLimits(sh.opts.cacheLimits)
+	sh.client.SetCache(cache)
+	sh.closeCache = func() { cache.Close() }
+	return nil
+}
+
+// splitWords splits a shell line on spaces, keeping text together that is
+// single or double quoted from the start of a word. A quote inside a word is
+// kept, so Schindler's List needs no escaping.
+func splitWords(line string) ([]string, error) {
+	words := []string{}
+	word := strings.Builder{}
+	inWord := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case (r == '"' || r == '\'') && !inWord:
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mtmsolver_history")
+}
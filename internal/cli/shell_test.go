@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func runShellScript(t *testing.T, script string, args ...string) (int, string, string) {
+	t.Helper()
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	args = append(append([]string{"shell"}, serverArgs(t)...), args...)
+	code := Run(args, strings.NewReader(script), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func apiCalls(t *testing.T, out string) []int {
+	t.Helper()
+	calls := []int{}
+	for _, match := range regexp.MustCompile(`api calls: (\d+)`).FindAllStringSubmatch(out, -1) {
+		n, _ := strconv.Atoi(match[1])
+		calls = append(calls, n)
+	}
+	return calls
+}
+
+func TestShellKeepsCacheWarm(t *testing.T) {
+	script := strings.Join([]string{
+		`path Midsommar Gravity`,
+		`stats`,
+		`path "Midsommar" 'Gravity'`,
+		`stats`,
+		`clear-cache`,
+		`path Midsommar Gravity`,
+		`stats`,
+	}, "\n")
+	code, stdout, stderr := runShellScript(t, script, "-history", "")
+	if code != ExitOK || stderr != "" {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if n := strings.Count(stdout, "Connects to: Gravity"); n != 3 {
+		t.Errorf("found %d paths in:\n%s", n, stdout)
+	}
+
+	calls := apiCalls(t, stdout)
+	if len(calls) != 3 {
+		t.Fatalf("got stats %v", calls)
+	}
+	// a warm query only looks up the two titles again
+	if warm := calls[1] - calls[0]; warm != 2 {
+		t.Errorf("warm query made %d calls", warm)
+	}
+	if cleared := calls[2] - calls[1]; cleared != calls[0] {
+		t.Errorf("query after clear-cache made %d calls, the first made %d", cleared, calls[0])
+	}
+}
+
+func TestShellCacheLimits(t *testing.T) {
+	script := strings.Join([]string{
+		`path Midsommar Gravity`,
+		`stats`,
+		`clear-cache`,
+		`path Midsommar Gravity`,
+		`stats`,
+	}, "\n")
+	code, stdout, stderr := runShellScript(t, script, "-history", "", "-cache-max-entries", "3")
+	if code != ExitOK || stderr != "" {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	matches := regexp.MustCompile(`cache entries: (\d+)`).FindAllStringSubmatch(stdout, -1)
+	if len(matches) != 2 {
+		t.Fatalf("got output:\n%s", stdout)
+	}
+	for _, match := range matches {
+		if n, _ := strconv.Atoi(match[1]); n == 0 || n > 3 {
+			t.Errorf("cache held %d entries, wanted at most 3", n)
+		}
+	}
+}
+
+func TestShellSearchFactorRefetchesCasts(t *testing.T) {
+	script := strings.Join([]string{
+		`set format csv`,
+		`actors "Reservoir Dogs"`,
+		`set searchfactor 1`,
+		`actors "Reservoir Dogs"`,
+	}, "\n")
+	code, stdout, stderr := runShellScript(t, script, "-history", "")
+	if code != ExitOK || stderr != "" {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	tables := strings.Split(stdout, "id,name,character")
+	if len(tables) != 3 {
+		t.Fatalf("got output:\n%s", stdout)
+	}
+	row := regexp.MustCompile(`(?m)^\d+,`)
+	full, cut := len(row.FindAllString(tables[1], -1)), len(row.FindAllString(tables[2], -1))
+	if full != 4 || cut != 1 {
+		t.Errorf("got %d actors before set searchfactor 1 and %d after:\n%s", full, cut, stdout)
+	}
+}
+
+func TestShellCommands(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	script := strings.Join([]string{
+		`actors "Reservoir Dogs"`,
+		`films "Tim Roth"`,
+		`set searchfactor 5`,
+		`set format csv`,
+		`set`,
+		`set color on`,
+		`bogus`,
+		`path "unterminated`,
+		`exit`,
+		`stats`,
+	}, "\n")
+	code, stdout, stderr := runShellScript(t, script, "-history", history)
+	if code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	for _, want := range []string{"Mr. Orange", "Pulp Fiction", "searchfactor 5", "format csv"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("%q missing from:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "api calls") {
+		t.Errorf("ran commands after exit")
+	}
+	for _, want := range []string{"unknown setting", "unknown command", "unterminated quote"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("%q missing from:\n%s", want, stderr)
+		}
+	}
+
+	dat, err := os.ReadFile(history)
+	if err != nil { t.Fatal(err) }
+	if lines := strings.Split(strings.TrimSpace(string(dat)), "\n"); len(lines) != 9 {
+		t.Errorf("history holds %d lines:\n%s", len(lines), dat)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := map[string][]string{
+		`path "Reservoir Dogs" 'Fight Club'`: {"path", "Reservoir Dogs", "Fight Club"},
+		`films Schindler's`:                  {"films", "Schindler's"},
+		`  set   format  json `:              {"set", "format", "json"},
+		`actors ""`:                          {"actors", ""},
+	}
+	for line, want := range tests {
+		got, err := splitWords(line)
+		if err != nil || strings.Join(got, "|") != strings.Join(want, "|") || len(got) != len(want) {
+			t.Errorf("%s: got %q, %v", line, got, err)
+		}
+	}
+}
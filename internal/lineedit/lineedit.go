@@ -0,0 +1,308 @@
+// Package lineedit reads lines from a terminal with cursor movement and
+// history, falling back to plain line reading when the input isn't one.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrInterrupted is returned by ReadLine when the line is abandoned with
+// Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+const defaultMaxHistory = 1000
+
+type Editor struct {
+	in         io.Reader
+	reader     *bufio.Reader
+	out        io.Writer
+	history    []string
+	maxHistory int
+	file       *os.File
+}
+
+func New(in io.Reader, out io.Writer) *Editor {
+	return &Editor{
+		in:         in,
+		reader:     bufio.NewReader(in),
+		out:        out,
+		maxHistory: defaultMaxHistory,
+	}
+}
+
+// LoadHistory reads earlier lines from path and appends every later line
+// added with AddHistory to it.
+func (e *Editor) LoadHistory(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil { return err }
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return err
+	}
+	if len(e.history) > e.maxHistory {
+		e.history = e.history[len(e.history) - e.maxHistory:]
+	}
+	e.file = file
+	return nil
+}
+
+// AddHistory records line, skipping blanks and repeats of the last line.
+func (e *Editor) AddHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history) - 1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > e.maxHistory {
+		e.history = e.history[1:]
+	}
+	if e.file != nil {
+		// losing a history line isn't worth failing the command over
+		e.file.WriteString(line + "\n")
+	}
+}
+
+func (e *Editor) History() []string {
+	return e.history
+}
+
+func (e *Editor) Close() error {
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// ReadLine shows prompt and returns the line entered without its newline. It
+// returns io.EOF at the end of the input or on Ctrl-D at an empty line.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if file, ok := e.in.(*os.File); ok {
+		if restore, err := makeRaw(int(file.Fd())); err == nil {
+			defer restore()
+			return e.edit(prompt)
+		}
+	}
+
+	fmt.Fprint(e.out, prompt)
+	line, err := e.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// edit runs the editor over raw key presses until the line is entered.
+func (e *Editor) edit(prompt string) (string, error) {
+	buf := []rune{}
+	cursor := 0
+	// history index being shown, len(history) is the line being typed
+	index := len(e.history)
+	typed := []rune{}
+
+	redraw := func() {
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if back := len(buf) - cursor; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+	show := func(i int) {
+		if index == len(e.history) {
+			typed = buf
+		}
+		index = i
+		if index == len(e.history) {
+			buf = typed
+		} else {
+			buf = []rune(e.history[index])
+		}
+		cursor = len(buf)
+		redraw()
+	}
+	insert := func(r rune) {
+		buf = append(buf[:cursor], append([]rune{r}, buf[cursor:]...)...)
+		cursor++
+		redraw()
+	}
+
+	redraw()
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			fmt.Fprint(e.out, "\r\n")
+			if err == io.EOF && len(buf) > 0 {
+				return string(buf), nil
+			}
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if cursor < len(buf) {
+				buf = append(buf[:cursor], buf[cursor + 1:]...)
+				redraw()
+			}
+		case 1: // Ctrl-A
+			cursor = 0
+			redraw()
+		case 5: // Ctrl-E
+			cursor = len(buf)
+			redraw()
+		case 2: // Ctrl-B
+			if cursor > 0 {
+				cursor--
+				redraw()
+			}
+		case 6: // Ctrl-F
+			if cursor < len(buf) {
+				cursor++
+				redraw()
+			}
+		case 8, 127: // backspace
+			if cursor > 0 {
+				buf = append(buf[:cursor - 1], buf[cursor:]...)
+				cursor--
+				redraw()
+			}
+		case 11: // Ctrl-K
+			buf = buf[:cursor]
+			redraw()
+		case 21: // Ctrl-U
+			buf = buf[cursor:]
+			cursor = 0
+			redraw()
+		case 23: // Ctrl-W
+			start := cursor
+			for start > 0 && buf[start - 1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start - 1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[cursor:]...)
+			cursor = start
+			redraw()
+		case 12: // Ctrl-L
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+			redraw()
+		case 16: // Ctrl-P
+			if index > 0 {
+				show(index - 1)
+			}
+		case 14: // Ctrl-N
+			if index < len(e.history) {
+				show(index + 1)
+			}
+		case 27: // escape sequence
+			key := e.readEscape()
+			switch key {
+			case keyUp:
+				if index > 0 {
+					show(index - 1)
+				}
+			case keyDown:
+				if index < len(e.history) {
+					show(index + 1)
+				}
+			case keyLeft:
+				if cursor > 0 {
+					cursor--
+					redraw()
+				}
+			case keyRight:
+				if cursor < len(buf) {
+					cursor++
+					redraw()
+				}
+			case keyHome:
+				cursor = 0
+				redraw()
+			case keyEnd:
+				cursor = len(buf)
+				redraw()
+			case keyDelete:
+				if cursor < len(buf) {
+					buf = append(buf[:cursor], buf[cursor + 1:]...)
+					redraw()
+				}
+			}
+		default:
+			if r >= ' ' {
+				insert(r)
+			}
+		}
+	}
+}
+
+type key int
+
+const (
+	keyUnknown key = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+// readEscape reads the rest of a CSI or SS3 sequence after the escape.
+func (e *Editor) readEscape() key {
+	intro, err := e.reader.ReadByte()
+	if err != nil || (intro != '[' && intro != 'O') {
+		return keyUnknown
+	}
+	params := []byte{}
+	for {
+		b, err := e.reader.ReadByte()
+		if err != nil { return keyUnknown }
+		if b >= 0x40 && b <= 0x7e {
+			switch {
+			case b == 'A':
+				return keyUp
+			case b == 'B':
+				return keyDown
+			case b == 'C':
+				return keyRight
+			case b == 'D':
+				return keyLeft
+			case b == 'H':
+				return keyHome
+			case b == 'F':
+				return keyEnd
+			case b == '~' && (string(params) == "1" || string(params) == "7"):
+				return keyHome
+			case b == '~' && (string(params) == "4" || string(params) == "8"):
+				return keyEnd
+			case b == '~' && string(params) == "3":
+				return keyDelete
+			}
+			return keyUnknown
+		}
+		params = append(params, b)
+	}
+}
@@ -0,0 +1,99 @@
+package lineedit
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	tests := []struct{
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "path a b\r", "path a b"},
+		{"insert after moving left", "abc\x1b[D\x1b[DX\r", "aXbc"},
+		{"backspace", "abcd\x7f\x7fe\r", "abe"},
+		{"home and end", "bc\x01a\x05d\r", "abcd"},
+		{"delete key", "abc\x01\x1b[3~\r", "bc"},
+		{"kill to end", "abcdef\x1b[D\x1b[D\x1b[D\x0b\r", "abc"},
+		{"delete word", "films Tim Roth\x17\x17Kate\r", "films Kate"},
+		{"unicode", "Amélie\x1b[D\x1b[D\x7fx\r", "Améxie"},
+	}
+	for _, test := range tests {
+		e := New(strings.NewReader(test.input), io.Discard)
+		got, err := e.edit("> ")
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, wanted %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEditHistory(t *testing.T) {
+	e := New(strings.NewReader("\x1b[A\x1b[A\r" + "new\x1b[A\x1b[B\r"), io.Discard)
+	e.AddHistory("first")
+	e.AddHistory("second")
+
+	got, err := e.edit("> ")
+	if err != nil || got != "first" {
+		t.Errorf("got %q, %v, wanted first", got, err)
+	}
+	got, err = e.edit("> ")
+	if err != nil || got != "new" {
+		t.Errorf("got %q, %v, wanted the typed line back", got, err)
+	}
+}
+
+func TestEditControlKeys(t *testing.T) {
+	e := New(strings.NewReader("abc\x03\x04"), io.Discard)
+	if _, err := e.edit("> "); err != ErrInterrupted {
+		t.Errorf("got %v, wanted interrupted", err)
+	}
+	if _, err := e.edit("> "); err != io.EOF {
+		t.Errorf("got %v, wanted EOF", err)
+	}
+}
+
+func TestReadLineWithoutTerminal(t *testing.T) {
+	e := New(strings.NewReader("one\r\ntwo"), io.Discard)
+	for _, want := range []string{"one", "two"} {
+		got, err := e.ReadLine("> ")
+		if err != nil || got != want {
+			t.Errorf("got %q, %v, wanted %q", got, err, want)
+		}
+	}
+	if _, err := e.ReadLine("> "); err != io.EOF {
+		t.Errorf("got %v, wanted EOF", err)
+	}
+}
+
+func TestHistoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	e := New(strings.NewReader(""), io.Discard)
+	if err := e.LoadHistory(path); err != nil { t.Fatal(err) }
+	e.AddHistory("path a b")
+	e.AddHistory("path a b")
+	e.AddHistory("  ")
+	e.AddHistory("stats")
+	e.Close()
+
+	dat, err := os.ReadFile(path)
+	if err != nil { t.Fatal(err) }
+	if string(dat) != "path a b\nstats\n" {
+		t.Errorf("history file holds %q", dat)
+	}
+
+	e = New(strings.NewReader(""), io.Discard)
+	if err := e.LoadHistory(path); err != nil { t.Fatal(err) }
+	defer e.Close()
+	if got := e.History(); len(got) != 2 || got[1] != "stats" {
+		t.Errorf("loaded %v", got)
+	}
+}
@@ -0,0 +1,187 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+)
+
+type Format string
+
+const (
+	Text     Format = "text"
+	JSON     Format = "json"
+	CSV      Format = "csv"
+	Markdown Format = "markdown"
+)
+
+// Formats lists every format Path can write, in the order they should be
+// offered.
+var Formats = []Format{Text, JSON, CSV, Markdown}
+
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	if strings.EqualFold(name, "md") {
+		return Markdown, nil
+	}
+	return "", fmt.Errorf("unknown output format %q", name)
+}
+
+// Path writes p to w in the given format.
+func Path(w io.Writer, format Format, p tmdbapi.Path) error {
+	switch format {
+	case Text:
+		_, err := io.WriteString(w, p.String())
+		return err
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	case CSV:
+		return pathCSV(w, p)
+	case Markdown:
+		return pathMarkdown(w, p)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// Paths writes several paths to w in the given format: a JSON array, CSV rows
+// numbered by path, or text and markdown separated by blank lines.
+func Paths(w io.Writer, format Format, paths []tmdbapi.Path) error {
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(paths)
+	case CSV:
+		writer := csv.NewWriter(w)
+		writer.Write(append([]string{"path"}, pathColumns...))
+		for i, p := range paths {
+			for _, row := range pathRows(p) {
+				writer.Write(append([]string{strconv.Itoa(i + 1)}, row...))
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	for i, p := range paths {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil { return err }
+		}
+		if err := Path(w, format, p); err != nil { return err }
+	}
+	return nil
+}
+
+var pathColumns = []string{
+	"hop", "from_id", "from_title", "to_id", "to_title",
+	"actor_id", "actor_name", "from_character", "to_character",
+}
+
+// pathCSV writes one row per linking actor of each hop.
+func pathCSV(w io.Writer, p tmdbapi.Path) error {
+	writer := csv.NewWriter(w)
+	writer.Write(pathColumns)
+	writer.WriteAll(pathRows(p))
+	return writer.Error()
+}
+
+func pathRows(p tmdbapi.Path) [][]string {
+	rows := [][]string{}
+	for i, hop := range p.Hops {
+		for _, actor := range hop.Actors {
+			rows = append(rows, []string{
+				strconv.Itoa(i + 1),
+				strconv.Itoa(hop.From.Id), hop.From.Title,
+				strconv.Itoa(hop.To.Id), hop.To.Title,
+				strconv.Itoa(actor.Id), actor.Name,
+				actor.FromCharacter, actor.ToCharacter,
+			})
+		}
+	}
+	return rows
+}
+
+func pathMarkdown(w io.Writer, p tmdbapi.Path) error {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "**%s** to **%s**, degree %d\n\n",
+		markdownEscape(p.From.Title), markdownEscape(p.To.Title), p.Degree,
+	)
+	b.WriteString("| Hop | From | To | Through |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for i, hop := range p.Hops {
+		actors := make([]string, len(hop.Actors))
+		for j, actor := range hop.Actors {
+			actors[j] = fmt.Sprintf("%s (%s / %s)",
+				actor.Name, actor.FromCharacter, actor.ToCharacter,
+			)
+		}
+		fmt.Fprintf(&b, "| %d | %s | %s | %s |\n",
+			i + 1,
+			markdownEscape(hop.From.Title),
+			markdownEscape(hop.To.Title),
+			markdownEscape(strings.Join(actors, ", ")),
+		)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Table writes rows under the given columns. JSON is an array of objects keyed
+// by column, text is aligned with tabs.
+func Table(w io.Writer, format Format, columns []string, rows [][]string) error {
+	switch format {
+	case Text:
+		writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, row := range rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	case JSON:
+		objects := make([]map[string]string, len(rows))
+		for i, row := range rows {
+			objects[i] = make(map[string]string, len(columns))
+			for j, column := range columns {
+				if j < len(row) {
+					objects[i][column] = row[j]
+				}
+			}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(objects)
+	case CSV:
+		writer := csv.NewWriter(w)
+		writer.Write(columns)
+		writer.WriteAll(rows)
+		return writer.Error()
+	case Markdown:
+		b := strings.Builder{}
+		b.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+		b.WriteString(strings.Repeat("| --- ", len(columns)) + "|\n")
+		for _, row := range rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = markdownEscape(cell)
+			}
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
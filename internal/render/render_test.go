@@ -0,0 +1,148 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+)
+
+var testPath = tmdbapi.Path{
+	From: tmdbapi.MovieResource{Id: 500, Title: "Reservoir Dogs"},
+	To:   tmdbapi.MovieResource{Id: 550, Title: "Fight Club"},
+	Hops: []tmdbapi.Hop{
+		{
+			From: tmdbapi.MovieResource{Id: 500, Title: "Reservoir Dogs"},
+			To:   tmdbapi.MovieResource{Id: 680, Title: "Pulp Fiction"},
+			Actors: []tmdbapi.Link{
+				{Id: 1037, Name: "Harvey Keitel", FromCharacter: "Mr. White", ToCharacter: "The Wolf"},
+				{Id: 3129, Name: "Tim Roth", FromCharacter: "Mr. Orange", ToCharacter: "Pumpkin"},
+			},
+		},
+		{
+			From: tmdbapi.MovieResource{Id: 680, Title: "Pulp Fiction"},
+			To:   tmdbapi.MovieResource{Id: 550, Title: "Fight Club"},
+			Actors: []tmdbapi.Link{
+				{Id: 1, Name: "Some | One", FromCharacter: "A, B", ToCharacter: "C"},
+			},
+		},
+	},
+	Degree: 2,
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range Formats {
+		got, err := ParseFormat(strings.ToUpper(string(format)))
+		if err != nil || got != format {
+			t.Errorf("got %q, %v for %q", got, err, format)
+		}
+	}
+	if got, _ := ParseFormat("md"); got != Markdown {
+		t.Errorf("md parsed as %q", got)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestJSONRoundTrips(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := Path(&buf, JSON, testPath); err != nil { t.Fatal(err) }
+	var got tmdbapi.Path
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil { t.Fatal(err) }
+	if got.Degree != 2 || got.Hops[0].Actors[1].ToCharacter != "Pumpkin" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := Path(&buf, CSV, testPath); err != nil { t.Fatal(err) }
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil { t.Fatal(err) }
+	if len(records) != 4 {
+		t.Fatalf("got %d records, wanted a header and 3 rows", len(records))
+	}
+	if records[3][6] != "Some | One" || records[3][7] != "A, B" {
+		t.Errorf("got row %v", records[3])
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := Path(&buf, Markdown, testPath); err != nil { t.Fatal(err) }
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[5], `Some \| One`) {
+		t.Errorf("pipe not escaped in %q", lines[5])
+	}
+}
+
+func TestText(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := Path(&buf, Text, testPath); err != nil { t.Fatal(err) }
+	want := "Starting from: Reservoir Dogs\n" +
+		"Through: Harvey Keitel, Tim Roth\n" +
+		"Connects to: Pulp Fiction\n" +
+		"Through: Some | One\n" +
+		"Connects to: Fight Club\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", buf.String(), want)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	paths := []tmdbapi.Path{testPath, testPath}
+
+	buf := bytes.Buffer{}
+	if err := Paths(&buf, CSV, paths); err != nil { t.Fatal(err) }
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil { t.Fatal(err) }
+	if len(records) != 7 || records[0][0] != "path" || records[6][0] != "2" {
+		t.Errorf("got records %v", records)
+	}
+
+	buf.Reset()
+	if err := Paths(&buf, JSON, paths); err != nil { t.Fatal(err) }
+	var got []tmdbapi.Path
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil { t.Fatal(err) }
+	if len(got) != 2 {
+		t.Errorf("got %d paths from JSON", len(got))
+	}
+
+	buf.Reset()
+	if err := Paths(&buf, Text, paths); err != nil { t.Fatal(err) }
+	if strings.Count(buf.String(), "Starting from:") != 2 || !strings.Contains(buf.String(), "Fight Club\n\nStarting") {
+		t.Errorf("got:\n%s", buf.String())
+	}
+}
+
+func TestTable(t *testing.T) {
+	columns := []string{"id", "title"}
+	rows := [][]string{{"500", "Reservoir Dogs"}, {"680", "Pulp Fiction"}}
+
+	buf := bytes.Buffer{}
+	if err := Table(&buf, JSON, columns, rows); err != nil { t.Fatal(err) }
+	var objects []map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &objects); err != nil { t.Fatal(err) }
+	if len(objects) != 2 || objects[1]["title"] != "Pulp Fiction" {
+		t.Errorf("got %v", objects)
+	}
+
+	buf.Reset()
+	if err := Table(&buf, CSV, columns, rows); err != nil { t.Fatal(err) }
+	if buf.String() != "id,title\n500,Reservoir Dogs\n680,Pulp Fiction\n" {
+		t.Errorf("got csv %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := Table(&buf, Markdown, columns, rows); err != nil { t.Fatal(err) }
+	if !strings.HasPrefix(buf.String(), "| id | title |\n| --- | --- |\n") {
+		t.Errorf("got markdown %q", buf.String())
+	}
+}
@@ -0,0 +1,313 @@
+// Package server exposes path search over an HTTP JSON API.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Server answers every request with one shared client, so concurrent and
+// later requests reuse its cache and coalesced fetches.
+type Server struct {
+	client  *tmdbapi.Client
+	timeout time.Duration
+	mux     *http.ServeMux
+}
+
+type errorBody struct {
+	Error      string                  `json:"error"`
+	Code       string                  `json:"code"`
+	Status     int                     `json:"status,omitempty"`
+	Candidates []tmdbapi.MovieResource `json:"candidates,omitempty"`
+}
+
+type actorPathBody struct {
+	Degree int        `json:"degree"`
+	Nodes  []nodeBody `json:"nodes"`
+}
+
+type mixedPathBody struct {
+	Nodes []nodeBody `json:"nodes"`
+}
+
+type nodeBody struct {
+	Kind string `json:"kind"`
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type searchBody struct {
+	Results []tmdbapi.MovieResource `json:"results"`
+}
+
+func New(client *tmdbapi.Client) *Server {
+	s := &Server{
+		client:  client,
+		timeout: defaultTimeout,
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/path", s.handlePath)
+	s.mux.HandleFunc("/actor-path", s.handleActorPath)
+	s.mux.HandleFunc("/mixed-path", s.handleMixedPath)
+	s.mux.HandleFunc("/movies/search", s.handleSearch)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	return s
+}
+
+// SetTimeout bounds how long a single request may search. Zero means requests
+// only end when the caller goes away.
+func (s *Server) SetTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "only GET is supported")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handlePath(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := endpoints(w, r)
+	if !ok {
+		return
+	}
+	from, ok = addYear(w, from, r.URL.Query().Get("from_year"))
+	if !ok {
+		return
+	}
+	to, ok = addYear(w, to, r.URL.Query().Get("to_year"))
+	if !ok {
+		return
+	}
+	s.respond(w, r, func(ctx context.Context) (any, error) {
+		return tmdbapi.GetPathContext(ctx, s.client, from, to)
+	})
+}
+
+func (s *Server) handleActorPath(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := endpoints(w, r)
+	if !ok {
+		return
+	}
+	s.respond(w, r, func(ctx context.Context) (any, error) {
+		path, err := tmdbapi.GetActorPathContext(ctx, s.client, from, to)
+		if err != nil { return nil, err }
+		nodes, err := s.nodeBodies(ctx, tmdbapi.ActorPathNodes(path))
+		if err != nil { return nil, err }
+		return actorPathBody{Degree: len(path) / 2, Nodes: nodes}, nil
+	})
+}
+
+// handleMixedPath searches between two movies or people, each given as a
+// title or as actor:name.
+func (s *Server) handleMixedPath(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := endpoints(w, r)
+	if !ok {
+		return
+	}
+	s.respond(w, r, func(ctx context.Context) (any, error) {
+		path, err := tmdbapi.GetMixedPathContext(
+			ctx, s.client, tmdbapi.ParseEndpoint(from), tmdbapi.ParseEndpoint(to),
+		)
+		if err != nil { return nil, err }
+		nodes, err := s.nodeBodies(ctx, path)
+		if err != nil { return nil, err }
+		return mixedPathBody{Nodes: nodes}, nil
+	})
+}
+
+func (s *Server) nodeBodies(ctx context.Context, path []tmdbapi.Node) ([]nodeBody, error) {
+	nodes := []nodeBody{}
+	for _, node := range path {
+		name, err := tmdbapi.NodeName(ctx, s.client, node)
+		if err != nil { return nil, err }
+		nodes = append(nodes, nodeBody{Kind: node.Kind.String(), Id: node.Id, Name: name})
+	}
+	return nodes, nil
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "query is required")
+		return
+	}
+	query, ok := addYear(w, query, r.URL.Query().Get("year"))
+	if !ok {
+		return
+	}
+	s.respond(w, r, func(ctx context.Context) (any, error) {
+		movies, err := s.client.SearchMoviesContext(ctx, query)
+		if err != nil { return nil, err }
+		return searchBody{Results: movies}, nil
+	})
+}
+
+// respond runs search under the request timeout and writes its result, or
+// streams its progress as server-sent events when the caller accepts them.
+func (s *Server) respond(
+	w http.ResponseWriter,
+	r *http.Request,
+	search func(ctx context.Context) (any, error),
+) {
+	ctx, cancel := s.context(r)
+	defer cancel()
+	if acceptsEvents(r) {
+		s.stream(ctx, w, search)
+		return
+	}
+
+	body, err := search(ctx)
+	if err != nil {
+		writeSearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// stream sends a progress event for each search event, then one result or
+// error event. The status of an error goes in its body, the response itself
+// has already started with 200.
+func (s *Server) stream(
+	ctx context.Context,
+	w http.ResponseWriter,
+	search func(ctx context.Context) (any, error),
+) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "internal_error", "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx = tmdbapi.WithProgress(ctx, func(p tmdbapi.Progress) {
+		writeEvent(w, "progress", p)
+		flusher.Flush()
+	})
+	body, err := search(ctx)
+	if err != nil {
+		status, code := classify(err)
+		writeEvent(w, "error", newErrorBody(err, code, status))
+	} else {
+		writeEvent(w, "result", body)
+	}
+	flusher.Flush()
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) context(r *http.Request) (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(r.Context(), s.timeout)
+	}
+	return context.WithCancel(r.Context())
+}
+
+func endpoints(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "from and to are required")
+		return "", "", false
+	}
+	return from, to, true
+}
+
+// addYear appends a year parameter to a title query the way ParseTitle reads
+// it back.
+func addYear(w http.ResponseWriter, title, year string) (string, bool) {
+	if year == "" {
+		return title, true
+	}
+	if _, err := strconv.Atoi(year); err != nil || len(year) != 4 {
+		writeError(w, http.StatusBadRequest, "bad_request", "years have four digits")
+		return "", false
+	}
+	return title + " (" + year + ")", true
+}
+
+// classify maps an error from the client or a search to a status and code.
+func classify(err error) (int, string) {
+	var notFound *tmdbapi.NotFoundError
+	var ambiguous *tmdbapi.AmbiguousTitleError
+	var apiErr *tmdbapi.APIError
+	switch {
+	case errors.As(err, &ambiguous):
+		return http.StatusConflict, "ambiguous_title"
+	case errors.As(err, &notFound):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, tmdbapi.ErrNoPath):
+		return http.StatusUnprocessableEntity, "no_path"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "timeout"
+	case errors.Is(err, context.Canceled):
+		// the caller has gone, nobody reads this
+		return http.StatusServiceUnavailable, "canceled"
+	case errors.Is(err, tmdbapi.ErrRateLimited):
+		return http.StatusServiceUnavailable, "rate_limited"
+	case errors.Is(err, tmdbapi.ErrUnauthorized):
+		// the server's own token was rejected, not the caller's fault
+		return http.StatusBadGateway, "upstream_unauthorized"
+	case errors.As(err, &apiErr):
+		return http.StatusBadGateway, "upstream_error"
+	}
+	return http.StatusInternalServerError, "internal_error"
+}
+
+func writeSearchError(w http.ResponseWriter, err error) {
+	var apiErr *tmdbapi.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(apiErr.RetryAfter.Seconds())))
+	}
+	status, code := classify(err)
+	writeJSON(w, status, newErrorBody(err, code, 0))
+}
+
+// newErrorBody describes err, listing the candidates of an ambiguous title.
+func newErrorBody(err error, code string, status int) errorBody {
+	body := errorBody{Error: err.Error(), Code: code, Status: status}
+	var ambiguous *tmdbapi.AmbiguousTitleError
+	if errors.As(err, &ambiguous) {
+		body.Candidates = ambiguous.Candidates
+	}
+	return body
+}
+
+func acceptsEvents(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func writeEvent(w io.Writer, event string, v any) {
+	dat, err := json.Marshal(v)
+	if err != nil { return }
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, dat)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorBody{Error: message, Code: code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbapi"
+	"github.com/BigStinko/mtmsolver/internal/tmdbtest"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *tmdbtest.Server, *Server) {
+	t.Helper()
+	tmdb := tmdbtest.NewServer()
+	t.Cleanup(tmdb.Close)
+
+	client := tmdbapi.New("Bearer test-token", time.Second * 5)
+	client.SetBaseURL(tmdb.BaseURL())
+	s := New(&client)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return server, tmdb, s
+}
+
+func get(t *testing.T, server *httptest.Server, path string, query url.Values, v any) int {
+	t.Helper()
+	res, err := http.Get(server.URL + path + "?" + query.Encode())
+	if err != nil { t.Fatal(err) }
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("%s: content type %q", path, ct)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil { t.Fatal(err) }
+	return res.StatusCode
+}
+
+func TestPath(t *testing.T) {
+	server, tmdb, _ := newTestServer(t)
+	query := url.Values{"from": {"Midsommar"}, "to": {"Gravity"}}
+
+	var path tmdbapi.Path
+	if status := get(t, server, "/path", query, &path); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	if path.Degree != 3 || len(path.Hops) != 3 {
+		t.Errorf("got %+v", path)
+	}
+
+	// the second request shares the cache, so only the titles are looked up
+	requests := tmdb.Requests()
+	if status := get(t, server, "/path", query, &path); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	if extra := tmdb.Requests() - requests; extra != 2 {
+		t.Errorf("second request made %d API calls", extra)
+	}
+}
+
+func TestPathYears(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	var body errorBody
+	query := url.Values{"from": {"Little Women"}, "to": {"Gravity"}}
+	get(t, server, "/path", query, &body)
+	if len(body.Candidates) != 2 {
+		t.Errorf("got candidates %v", body.Candidates)
+	}
+
+	var path tmdbapi.Path
+	query.Set("from_year", "2019")
+	if status := get(t, server, "/path", query, &path); status != http.StatusOK || path.From.Id != 331482 {
+		t.Errorf("got %d %+v", status, path)
+	}
+
+	var search searchBody
+	query = url.Values{"query": {"little women"}, "year": {"1994"}}
+	if status := get(t, server, "/movies/search", query, &search); status != http.StatusOK ||
+		len(search.Results) != 1 || search.Results[0].Id != 9587 {
+		t.Errorf("got %d %+v", status, search)
+	}
+}
+
+func TestActorPath(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	var body actorPathBody
+	query := url.Values{"from": {"Florence Pugh"}, "to": {"George Clooney"}}
+	if status := get(t, server, "/actor-path", query, &body); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	if body.Degree != 2 || len(body.Nodes) != 5 || body.Nodes[4].Name != "George Clooney" {
+		t.Errorf("got %+v", body)
+	}
+}
+
+func TestMixedPath(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	var body mixedPathBody
+	query := url.Values{"from": {"Fight Club"}, "to": {"actor:John Malkovich"}}
+	if status := get(t, server, "/mixed-path", query, &body); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	if len(body.Nodes) != 4 || body.Nodes[0].Name != "Fight Club" ||
+		body.Nodes[3].Kind != "actor" || body.Nodes[3].Name != "John Malkovich" {
+		t.Errorf("got %+v", body)
+	}
+}
+
+func TestSearchAndHealth(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	var search searchBody
+	if status := get(t, server, "/movies/search", url.Values{"query": {"pulp"}}, &search); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	if len(search.Results) != 1 || search.Results[0].Id != 680 {
+		t.Errorf("got %+v", search)
+	}
+
+	var health map[string]string
+	if status := get(t, server, "/healthz", nil, &health); status != http.StatusOK || health["status"] != "ok" {
+		t.Errorf("got %d %v", status, health)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server, tmdb, s := newTestServer(t)
+	tests := []struct{
+		path   string
+		query  url.Values
+		status int
+		code   string
+	}{
+		{"/path", url.Values{"from": {"Midsommar"}}, http.StatusBadRequest, "bad_request"},
+		{"/movies/search", nil, http.StatusBadRequest, "bad_request"},
+		{"/path", url.Values{"from": {"Midsommar"}, "to": {"Not A Real Movie"}}, http.StatusNotFound, "not_found"},
+		{"/actor-path", url.Values{"from": {"Harvey Keitel"}, "to": {"Michael Caine"}}, http.StatusUnprocessableEntity, "no_path"},
+		{"/path", url.Values{"from": {"Little Women"}, "to": {"Gravity"}}, http.StatusConflict, "ambiguous_title"},
+		{"/path", url.Values{"from": {"Gravity"}, "to": {"Fight Club"}, "to_year": {"99"}}, http.StatusBadRequest, "bad_request"},
+	}
+	for _, test := range tests {
+		var body errorBody
+		status := get(t, server, test.path, test.query, &body)
+		if status != test.status || body.Code != test.code || body.Error == "" {
+			t.Errorf("%s %v: got %d %+v, wanted %d %s",
+				test.path, test.query, status, body, test.status, test.code,
+			)
+		}
+	}
+
+	tmdb.SetLatency(50 * time.Millisecond)
+	s.SetTimeout(10 * time.Millisecond)
+	var body errorBody
+	query := url.Values{"from": {"Kickboxer"}, "to": {"Dirty Rotten Scoundrels"}}
+	if status := get(t, server, "/path", query, &body); status != http.StatusGatewayTimeout {
+		t.Errorf("got %d %+v, wanted a timeout", status, body)
+	}
+
+	res, err := http.Post(server.URL + "/path", "application/json", nil)
+	if err != nil { t.Fatal(err) }
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST got %d", res.StatusCode)
+	}
+
+	tmdb.SetLatency(0)
+	s.SetTimeout(time.Minute)
+	tmdb.SetToken("another-token")
+	body = errorBody{}
+	query = url.Values{"from": {"tmdb:550"}, "to": {"Gravity"}}
+	if status := get(t, server, "/path", query, &body); status != http.StatusBadGateway || body.Code != "upstream_unauthorized" {
+		t.Errorf("rejected token: got %d %+v", status, body)
+	}
+}
+
+type event struct {
+	name string
+	data string
+}
+
+func getEvents(t *testing.T, server *httptest.Server, path string, query url.Values) []event {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, server.URL + path + "?" + query.Encode(), nil)
+	if err != nil { t.Fatal(err) }
+	req.Header.Set("Accept", "text/event-stream")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil { t.Fatal(err) }
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+
+	events := []event{}
+	scanner := bufio.NewScanner(res.Body)
+	current := event{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, current)
+			current = event{}
+		}
+	}
+	return events
+}
+
+func TestStreamProgress(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	query := url.Values{"from": {"Kickboxer"}, "to": {"Dirty Rotten Scoundrels"}}
+	events := getEvents(t, server, "/path", query)
+	if len(events) < 3 {
+		t.Fatalf("got events %v", events)
+	}
+
+	for _, e := range events[:len(events) - 1] {
+		var p tmdbapi.Progress
+		if err := json.Unmarshal([]byte(e.data), &p); e.name != "progress" || err != nil {
+			t.Errorf("got %s event %s: %v", e.name, e.data, err)
+		}
+	}
+	last := events[len(events) - 1]
+	var path tmdbapi.Path
+	if err := json.Unmarshal([]byte(last.data), &path); last.name != "result" || err != nil {
+		t.Fatalf("got %s event %s: %v", last.name, last.data, err)
+	}
+	if path.Degree != 3 {
+		t.Errorf("got degree %d", path.Degree)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	query := url.Values{"from": {"Harvey Keitel"}, "to": {"Michael Caine"}}
+	events := getEvents(t, server, "/actor-path", query)
+	last := events[len(events) - 1]
+	var body errorBody
+	if err := json.Unmarshal([]byte(last.data), &body); last.name != "error" || err != nil {
+		t.Fatalf("got %s event %s: %v", last.name, last.data, err)
+	}
+	if body.Status != http.StatusUnprocessableEntity || body.Code != "no_path" {
+		t.Errorf("got %+v", body)
+	}
+}
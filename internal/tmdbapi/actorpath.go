@@ -0,0 +1,101 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// GetActorPath finds the shortest chain of shared movies between two people.
+// The result alternates actor and movie ids, starting and ending with an
+// actor, so its Bacon number is len(path) / 2.
+func GetActorPath(s Source, src, dest string) ([]int, error) {
+	return GetActorPathContext(context.Background(), s, src, dest)
+}
+
+func GetActorPathContext(
+	ctx context.Context,
+	s Source,
+	src, dest string,
+) ([]int, error) {
+	srcRes, err := s.GetActorFromNameContext(ctx, src)
+	if err != nil { return nil, err }
+	if srcRes == NoName {
+		return nil, actorNotFoundError(src)
+	}
+	destRes, err := s.GetActorFromNameContext(ctx, dest)
+	if err != nil { return nil, err }
+	if destRes == NoName {
+		return nil, actorNotFoundError(dest)
+	}
+	if destRes.Id == srcRes.Id {
+		return []int{srcRes.Id}, nil
+	}
+
+	actors, err := runParallelSearch(ctx, s, coStars(s), srcRes.Id, destRes.Id)
+	if err != nil { return nil, err }
+
+	path := []int{actors[0]}
+	for i := 1; i < len(actors); i++ {
+		movie, err := sharedMovie(ctx, s, actors[i - 1], actors[i])
+		if err != nil { return nil, err }
+		path = append(path, movie, actors[i])
+	}
+	return path, nil
+}
+
+// coStars expands an actor to everyone credited alongside them.
+func coStars(s Source) expandFunc {
+	return func(ctx context.Context, actorId int) (map[int]struct{}, error) {
+		movies, err := s.GetMoviesContext(ctx, actorId)
+		if err != nil { return nil, err }
+
+		neighbors := make(map[int]struct{})
+		for movie := range movies {
+			actors, err := s.GetActorsContext(ctx, movie)
+			if err != nil { return nil, err }
+			for actor := range actors {
+				neighbors[actor] = struct{}{}
+			}
+		}
+		return neighbors, nil
+	}
+}
+
+// sharedMovie returns the lowest id movie of left's that credits right, the
+// same relation coStars expands along. The search from dest expands the other
+// way, so when a cut cast leaves right out of all of them it falls back to the
+// lowest id movie of right's that credits left.
+func sharedMovie(ctx context.Context, s Source, left, right int) (int, error) {
+	movie, err := creditingMovie(ctx, s, left, right)
+	if err != nil || movie != 0 { return movie, err }
+	movie, err = creditingMovie(ctx, s, right, left)
+	if err != nil || movie != 0 { return movie, err }
+	return 0, errors.New("Failure finding shared movie")
+}
+
+// creditingMovie returns the lowest id movie of actor's whose cast lists
+// other, or 0 when there is none.
+func creditingMovie(ctx context.Context, s Source, actor, other int) (int, error) {
+	movies, err := s.GetMoviesContext(ctx, actor)
+	if err != nil { return 0, err }
+
+	ids := make([]int, 0, len(movies))
+	for movie := range movies {
+		ids = append(ids, movie)
+	}
+	slices.Sort(ids)
+
+	for _, movie := range ids {
+		actors, err := s.GetActorsContext(ctx, movie)
+		if err != nil { return 0, err }
+		if _, ok := actors[other]; ok {
+			return movie, nil
+		}
+	}
+	return 0, nil
+}
+
+func PrintActorPath(s Source, path []int) error {
+	return PrintPath(s, ActorPathNodes(path))
+}
@@ -0,0 +1,105 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestActorPath(t *testing.T) {
+	tests := map[int]struct{
+		src string
+		dest string
+		expected []int
+	}{
+		0: {
+			src: "Florence Pugh",
+			dest: "George Clooney",
+			expected: []int{1373737, 331482, 5064, 10315, 1461},
+		},
+		1: {
+			src: "Harvey Keitel",
+			dest: "Tim Roth",
+			expected: []int{1037, 500, 3129},
+		},
+		2: {
+			src: "Jean-Claude Van Damme",
+			dest: "Steve Martin",
+			expected: []int{15111, 76163, 16483, 13251, 3895, 10141, 67773},
+		},
+		3: {
+			src: "Edward Norton",
+			dest: "Edward Norton",
+			expected: []int{819},
+		},
+	}
+	sources := map[string]Source{
+		"memory": newTestSource(t),
+		"client": newTestClient(t),
+	}
+
+	for name, source := range sources {
+		for _, test := range tests {
+			path, err := GetActorPath(source, test.src, test.dest)
+			if err != nil {
+				t.Errorf("%s: %s, for %s to %s", name, err.Error(), test.src, test.dest)
+				continue
+			}
+			if !slices.Equal(path, test.expected) {
+				t.Errorf("%s: path for %s to %s was %v, wanted %v",
+					name, test.src, test.dest, path, test.expected,
+				)
+			}
+		}
+	}
+}
+
+func TestActorPathErrors(t *testing.T) {
+	source := newTestSource(t)
+
+	if _, err := GetActorPath(source, "Harvey Keitel", "Michael Caine"); !errors.Is(err, ErrNoPath) {
+		t.Errorf("got error %v, wanted ErrNoPath", err)
+	}
+	if _, err := GetActorPath(source, "Harvey Keitel", "Nobody At All"); err == nil {
+		t.Errorf("expected error for missing actor")
+	}
+}
+
+// cutCasts is a MemorySource whose casts leave some actors out, as the
+// client's do once the searchfactor cuts them short.
+type cutCasts struct {
+	*MemorySource
+	cut map[int]int
+}
+
+func (s cutCasts) GetActorsContext(ctx context.Context, movieId int) (map[int]struct{}, error) {
+	actors, err := s.MemorySource.GetActorsContext(ctx, movieId)
+	if err != nil { return nil, err }
+	out := map[int]struct{}{}
+	for actor := range actors {
+		if s.cut[movieId] != actor {
+			out[actor] = struct{}{}
+		}
+	}
+	return out, nil
+}
+
+func TestActorPathThroughCutCast(t *testing.T) {
+	// A and C share movie 1, and C and X share movie 2, whose cast leaves X
+	// out. Only X's filmography links C to X.
+	source := cutCasts{MemorySource: NewMemorySource(), cut: map[int]int{2: 30}}
+	for id, name := range map[int]string{10: "A", 20: "C", 30: "X"} {
+		source.AddActor(ActorResource{Id: id, Name: name})
+	}
+	for _, credit := range [][2]int{{1, 10}, {1, 20}, {2, 20}, {2, 30}} {
+		source.AddMovie(MovieResource{Id: credit[0], Title: "movie"})
+		source.AddCredit(credit[0], credit[1], "role")
+	}
+
+	path, err := GetActorPath(source, "A", "X")
+	if err != nil { t.Fatal(err) }
+	if want := []int{10, 1, 20, 2, 30}; !slices.Equal(path, want) {
+		t.Errorf("got %v, wanted %v", path, want)
+	}
+}
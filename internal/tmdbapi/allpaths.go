@@ -0,0 +1,106 @@
+package tmdbapi
+
+import (
+	"context"
+	"slices"
+)
+
+// AllShortestPaths finds every shortest chain of shared actors between two
+// movies, given like GetPath's. The paths are ordered by the movie ids along
+// them, and a limit above zero keeps only the first limit of them.
+func AllShortestPaths(s Source, src, dest string, limit int) ([]Path, error) {
+	return AllShortestPathsContext(context.Background(), s, src, dest, limit)
+}
+
+func AllShortestPathsContext(
+	ctx context.Context,
+	s Source,
+	src, dest string,
+	limit int,
+) ([]Path, error) {
+	srcRes, err := resolveMovie(ctx, s, src)
+	if err != nil { return nil, err }
+	destRes, err := resolveMovie(ctx, s, dest)
+	if err != nil { return nil, err }
+	if destRes.Id == srcRes.Id {
+		return []Path{{From: srcRes, To: destRes, Hops: []Hop{}}}, nil
+	}
+
+	result, err := searchGraph(ctx, s, s.GetNeighborsContext, srcRes.Id, destRes.Id, searchRules{})
+	if err != nil { return nil, err }
+
+	meeting := result.meetingDepth()
+	paths := []Path{}
+	for _, movies := range result.paths(limit) {
+		path, err := buildPath(ctx, s, movies, meeting, srcRes, destRes)
+		if err != nil { return nil, err }
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// paths lists the shortest paths through every meeting node in lexicographic
+// order, stopping after limit of them when limit is above zero.
+func (r *searchResult) paths(limit int) [][]int {
+	return r.pathsWhere(limit, nil)
+}
+
+// pathsWhere is paths leaving out every path that skip rejects a node of.
+// skip is given the path so far and the node that would come next.
+func (r *searchResult) pathsWhere(limit int, skip func(path []int, next int) bool) [][]int {
+	// children runs the src side's parent links the other way, keeping only
+	// the nodes that lead to a meeting node
+	children := map[int][]int{}
+	onPath := map[int]bool{}
+	queue := slices.Clone(r.meetings)
+	for _, meeting := range r.meetings {
+		onPath[meeting] = true
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, parent := range r.src.parents[node] {
+			children[parent] = append(children[parent], node)
+			if !onPath[parent] {
+				onPath[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	meetingDepth := r.meetingDepth()
+	out := [][]int{}
+	path := []int{}
+	var walk func(node int) bool
+	walk = func(node int) bool {
+		path = append(path, node)
+		defer func() { path = path[:len(path) - 1] }()
+
+		next := children[node]
+		if len(path) > meetingDepth {
+			// past the meeting, head back towards dest, leaving out branches
+			// to nodes the dest side never reached
+			if node == r.dest.root {
+				out = append(out, slices.Clone(path))
+				return limit > 0 && len(out) >= limit
+			}
+			if _, ok := r.dest.depth[node]; !ok {
+				return false
+			}
+			next = r.dest.parents[node]
+		}
+		next = slices.Clone(next)
+		slices.Sort(next)
+		for _, child := range next {
+			if skip != nil && skip(path, child) {
+				continue
+			}
+			if walk(child) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(r.src.root)
+	return out
+}
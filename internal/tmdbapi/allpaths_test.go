@@ -0,0 +1,150 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestAllShortestPaths(t *testing.T) {
+	// two routes from 1 to 4, through 2 or 3, and a longer one through 5 and 6
+	source := NewMemorySource()
+	for id := 1; id <= 6; id++ {
+		source.AddMovie(MovieResource{Id: id, Title: "movie"})
+	}
+	for _, credit := range [][2]int{
+		{1, 10}, {2, 10}, {2, 20}, {4, 20},
+		{1, 11}, {3, 11}, {3, 21}, {4, 21},
+		{1, 12}, {5, 12}, {5, 22}, {6, 22}, {6, 23}, {4, 23},
+	} {
+		source.AddActor(ActorResource{Id: credit[1], Name: "actor"})
+		source.AddCredit(credit[0], credit[1], "role")
+	}
+
+	paths, err := AllShortestPaths(source, "tmdb:1", "tmdb:4", 0)
+	if err != nil { t.Fatal(err) }
+	got := [][]int{}
+	for _, path := range paths {
+		got = append(got, path.Movies())
+	}
+	want := [][]int{{1, 2, 4}, {1, 3, 4}}
+	if !slices.EqualFunc(got, want, slices.Equal[[]int]) {
+		t.Errorf("got paths %v, wanted %v", got, want)
+	}
+	if len(paths) == 2 && paths[1].Hops[1].Actors[0].Id != 21 {
+		t.Errorf("second path links through %+v", paths[1].Hops[1])
+	}
+
+	paths, err = AllShortestPaths(source, "tmdb:1", "tmdb:4", 1)
+	if err != nil { t.Fatal(err) }
+	if len(paths) != 1 || !slices.Equal(paths[0].Movies(), want[0]) {
+		t.Errorf("limit 1 got %v", paths)
+	}
+
+	path, err := GetPath(source, "tmdb:1", "tmdb:4")
+	if err != nil { t.Fatal(err) }
+	if !slices.Equal(path.Movies(), want[0]) {
+		t.Errorf("GetPath got %v, wanted the first shortest path", path.Movies())
+	}
+}
+
+// countShortest counts the shortest paths from src to dest by dynamic
+// programming over a plain BFS.
+func countShortest(t *testing.T, expand expandFunc, src, dest int) int {
+	t.Helper()
+	depth := map[int]int{src: 0}
+	count := map[int]int{src: 1}
+	queue := []int{src}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		neighbors, err := expand(context.Background(), node)
+		if err != nil { t.Fatal(err) }
+		for neighbor := range neighbors {
+			d, ok := depth[neighbor]
+			if !ok {
+				depth[neighbor] = depth[node] + 1
+				queue = append(queue, neighbor)
+				d = depth[neighbor]
+			}
+			if d == depth[node] + 1 {
+				count[neighbor] += count[node]
+			}
+		}
+	}
+	return count[dest]
+}
+
+func TestAllShortestPathsMatchCounts(t *testing.T) {
+	for seed := int64(1); seed <= 30; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		source := randomSource(r, 40, 40, 90)
+		expand := source.GetNeighborsContext
+		for i := 0; i < 10; i++ {
+			src, dest := 1 + r.Intn(40), 1 + r.Intn(40)
+			want := countShortest(t, expand, src, dest)
+			if src == dest || want == 0 {
+				continue
+			}
+			result, err := searchGraph(context.Background(), source, expand, src, dest, searchRules{})
+			if err != nil { t.Fatal(err) }
+
+			all := result.paths(0)
+			if len(all) != want {
+				t.Fatalf("seed %d, %d to %d: got %d paths, wanted %d", seed, src, dest, len(all), want)
+			}
+			hops := plainBFS(t, expand, src, dest)
+			for j, path := range all {
+				checkShortest(t, expand, path, nil, src, dest, hops)
+				if j > 0 && slices.Compare(all[j - 1], path) >= 0 {
+					t.Errorf("seed %d: paths out of order: %v then %v", seed, all[j - 1], path)
+				}
+			}
+			if !slices.Equal(result.first, all[0]) {
+				t.Errorf("seed %d: first path %v isn't %v", seed, result.first, all[0])
+			}
+			if limited := result.paths(2); len(limited) != min(2, want) {
+				t.Errorf("seed %d: limit 2 gave %d paths", seed, len(limited))
+			}
+		}
+	}
+}
+
+func TestPathsOverOneWayNeighborsReachDest(t *testing.T) {
+	for seed := int64(1); seed <= 40; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		expand := oneWayGraph(r, 30, 60)
+		for i := 0; i < 10; i++ {
+			src, dest := 1 + r.Intn(30), 1 + r.Intn(30)
+			if src == dest {
+				continue
+			}
+			result, err := searchGraph(context.Background(), NewMemorySource(), expand, src, dest, searchRules{})
+			if errors.Is(err, ErrNoPath) {
+				continue
+			}
+			if err != nil { t.Fatal(err) }
+
+			// each side follows its own edges, so the hops after the meeting
+			// run from dest's end
+			meeting := result.meetingDepth()
+			for _, path := range result.paths(0) {
+				if path[0] != src || path[len(path) - 1] != dest {
+					t.Fatalf("seed %d, %d to %d: %v doesn't join them", seed, src, dest, path)
+				}
+				for j := 1; j < len(path); j++ {
+					from, to := path[j - 1], path[j]
+					if j > meeting {
+						from, to = to, from
+					}
+					neighbors, _ := expand(context.Background(), from)
+					if _, ok := neighbors[to]; !ok {
+						t.Fatalf("seed %d: %v has no edge %d to %d", seed, path, from, to)
+					}
+				}
+			}
+		}
+	}
+}
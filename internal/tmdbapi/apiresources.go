@@ -1,8 +1,11 @@
 package tmdbapi
 
 type MovieResource struct {
-	Title string `json:"title"`
-	Id    int	 `json:"id"`
+	Title       string  `json:"title"`
+	Id          int	    `json:"id"`
+	ImdbId      string  `json:"imdb_id,omitempty"`
+	ReleaseDate string  `json:"release_date,omitempty"`
+	Popularity  float64 `json:"popularity,omitempty"`
 }
 
 type ActorResource struct {
@@ -17,6 +20,10 @@ type MovieQueryResult struct {
 	TotalResults int             `json:"total_results"`
 }
 
+type FindResult struct {
+	MovieResults []MovieResource `json:"movie_results"`
+}
+
 type ActorQueryResult struct {
 	Results      []ActorResource `json:"results"`
 	Page         int             `json:"page"`
@@ -27,6 +34,8 @@ type ActorQueryResult struct {
 type Credits struct {
 	Cast []struct{
 		Id        int    `json:"id"`
+		Name      string `json:"name"`
+		Title     string `json:"title"`
 		Character string `json:"character"`
 	} `json:"cast"`
 }
@@ -0,0 +1,154 @@
+package tmdbapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+type CassetteMode int
+
+const (
+	CassettePassthrough CassetteMode = iota
+	CassetteRecord
+	CassetteReplay
+)
+
+// CassetteMissError is returned in replay mode for a request that has no
+// recorded response.
+type CassetteMissError struct {
+	Method string
+	URL    string
+}
+
+func (e *CassetteMissError) Error() string {
+	return fmt.Sprintf("no cassette recording for %s %s", e.Method, e.URL)
+}
+
+// Cassette is an http.RoundTripper that records responses to a directory and
+// replays them. In record mode a request already on disk is replayed, anything
+// else is fetched and written out unless it failed with a retryable status.
+type Cassette struct {
+	dir  string
+	mode CassetteMode
+	next http.RoundTripper
+}
+
+type recording struct {
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	RequestHeader http.Header `json:"request_header"`
+	StatusCode    int         `json:"status_code"`
+	Header        http.Header `json:"header"`
+	Body          string      `json:"body"`
+}
+
+func NewCassette(dir string, mode CassetteMode, next http.RoundTripper) *Cassette {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Cassette{
+		dir:  dir,
+		mode: mode,
+		next: next,
+	}
+}
+
+func (c *Client) SetCassette(dir string, mode CassetteMode) {
+	c.httpClient.Transport = NewCassette(dir, mode, c.httpClient.Transport)
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.mode == CassettePassthrough {
+		return c.next.RoundTrip(req)
+	}
+
+	path := c.recordingPath(req)
+	rec, err := readRecording(path)
+	if err == nil {
+		return rec.response(req), nil
+	}
+	if !os.IsNotExist(err) { return nil, err }
+	if c.mode == CassetteReplay {
+		return nil, &CassetteMissError{Method: req.Method, URL: req.URL.RequestURI()}
+	}
+
+	res, err := c.next.RoundTrip(req)
+	if err != nil { return nil, err }
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil { return nil, err }
+
+	header := req.Header.Clone()
+	header.Del("Authorization")
+	rec = recording{
+		Method:        req.Method,
+		URL:           req.URL.RequestURI(),
+		RequestHeader: header,
+		StatusCode:    res.StatusCode,
+		Header:        res.Header.Clone(),
+		Body:          string(body),
+	}
+	// rate limits and server errors are passed on for the client to retry
+	// rather than recorded, or every retry would replay them
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return rec.response(req), nil
+	}
+	if err := writeRecording(path, rec); err != nil { return nil, err }
+
+	return rec.response(req), nil
+}
+
+func (c *Cassette) recordingPath(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.RequestURI()))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16]) + ".json")
+}
+
+func (r recording) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewBufferString(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func readRecording(path string) (recording, error) {
+	var rec recording
+	dat, err := os.ReadFile(path)
+	if err != nil { return rec, err }
+	err = json.Unmarshal(dat, &rec)
+	return rec, err
+}
+
+func writeRecording(path string, rec recording) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { return err }
+
+	dat, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil { return err }
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".recording-*")
+	if err != nil { return err }
+	if _, err := tmp.Write(dat); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,93 @@
+package tmdbapi
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbtest"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	server := tmdbtest.NewServer()
+
+	recorder := New("Bearer secret-token", time.Second * 5)
+	recorder.SetBaseURL(server.BaseURL())
+	recorder.SetCassette(dir, CassetteRecord)
+	recorded, err := GetPath(&recorder, "Midsommar", "Gravity")
+	if err != nil { t.Fatal(err) }
+	requests := server.Requests()
+	server.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil { t.Fatal(err) }
+	if len(files) == 0 {
+		t.Fatal("nothing was recorded")
+	}
+	for _, file := range files {
+		dat, err := os.ReadFile(file)
+		if err != nil { t.Fatal(err) }
+		if strings.Contains(string(dat), "secret-token") ||
+			strings.Contains(string(dat), "Authorization") {
+			t.Fatalf("%s contains the authorization header", file)
+		}
+	}
+	if len(files) > requests {
+		t.Errorf("recorded %d files for %d requests", len(files), requests)
+	}
+
+	replayer := New("Bearer secret-token", time.Second * 5)
+	replayer.SetBaseURL(server.BaseURL())
+	replayer.SetCassette(dir, CassetteReplay)
+	replayed, err := GetPath(&replayer, "Midsommar", "Gravity")
+	if err != nil { t.Fatal(err) }
+	if replayed.Degree != recorded.Degree {
+		t.Errorf("replayed path %v, recorded %v", replayed, recorded)
+	}
+
+	_, err = replayer.GetMovieFromTitle("Pulp Fiction")
+	var miss *CassetteMissError
+	if !errors.As(err, &miss) {
+		t.Errorf("got error %v, wanted cassette miss", err)
+	}
+}
+
+func TestCassetteSkipsRetryableFailures(t *testing.T) {
+	dir := t.TempDir()
+	client, server := newRetryingClient(t)
+	client.SetCassette(dir, CassetteRecord)
+	server.FailNext(1, http.StatusServiceUnavailable, "")
+
+	if _, err := client.GetActors(550); err != nil { t.Fatal(err) }
+	if requests := server.Requests(); requests != 2 {
+		t.Errorf("server got %d requests, wanted the 503 and its retry", requests)
+	}
+
+	replayer := New("Bearer test-token", time.Second * 5)
+	replayer.SetBaseURL(server.BaseURL())
+	replayer.SetCassette(dir, CassetteReplay)
+	actors, err := replayer.GetActors(550)
+	if err != nil { t.Fatal(err) }
+	if len(actors) != 3 {
+		t.Errorf("replayed actors %v, wanted the 200's", actors)
+	}
+}
+
+func TestCassettePassthrough(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestClient(t)
+	client.SetCassette(dir, CassettePassthrough)
+
+	if _, err := client.GetMovieFromTitle("Fight Club"); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 0 {
+		t.Errorf("passthrough wrote %v", files)
+	}
+}
@@ -0,0 +1,275 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Constraints shape the path GetPathConstrained may return.
+type Constraints struct {
+	BannedActors []int
+	BannedMovies []int
+	// Via lists movies and actors the path has to pass through, in order. An
+	// actor is passed through when they link two movies of the path.
+	Via []Node
+	// MaxHops caps the number of movie to movie hops, 0 for no cap.
+	MaxHops int
+}
+
+// searchRules are the constraints searchGraph applies as it expands, on the
+// int keys of the graph being searched.
+type searchRules struct {
+	banned  map[int]bool
+	maxHops int
+}
+
+// GetPathConstrained finds the shortest chain of shared actors between two
+// movies, given like GetPath's, that keeps to c. The search runs over movies
+// and actors alike so banned actors are never used as a link, and passes the
+// waypoints in a single search of that graph layered by how many of them were
+// reached. As with GetPath an actor may link more than one hop, but no movie
+// is passed twice, and each hop lists just the actor the path goes through
+// there. In the rare case that every shortest way through the waypoints
+// passes a movie twice, the path is instead made of the shortest leg to each
+// waypoint in turn and may be longer than needed. ErrNoPath means no path
+// keeps to c.
+func GetPathConstrained(s Source, src, dest string, c Constraints) (Path, error) {
+	return GetPathConstrainedContext(context.Background(), s, src, dest, c)
+}
+
+func GetPathConstrainedContext(
+	ctx context.Context,
+	s Source,
+	src, dest string,
+	c Constraints,
+) (Path, error) {
+	srcRes, err := resolveMovie(ctx, s, src)
+	if err != nil { return Path{}, err }
+	destRes, err := resolveMovie(ctx, s, dest)
+	if err != nil { return Path{}, err }
+
+	banned := map[int]bool{}
+	for _, actor := range c.BannedActors {
+		banned[nodeKey(Node{Kind: ActorKind, Id: actor})] = true
+	}
+	for _, movie := range c.BannedMovies {
+		banned[nodeKey(Node{Kind: MovieKind, Id: movie})] = true
+	}
+	stops := []int{srcRes.Id}
+	for _, node := range c.Via {
+		stops = append(stops, nodeKey(node))
+	}
+	stops = append(stops, destRes.Id)
+	for i, stop := range stops {
+		if banned[stop] {
+			return Path{}, fmt.Errorf("%s %d is banned but the path has to pass through it",
+				keyNode(stop).Kind, keyNode(stop).Id,
+			)
+		}
+		if slices.Contains(stops[:i], stop) {
+			return Path{}, fmt.Errorf("%s %d appears twice in the path's stops",
+				keyNode(stop).Kind, keyNode(stop).Id,
+			)
+		}
+	}
+	if len(stops) == 2 && srcRes.Id == destRes.Id {
+		return Path{From: srcRes, To: destRes, Hops: []Hop{}}, nil
+	}
+
+	expand := bipartiteNeighbors(s)
+	keys, err := throughWaypoints(ctx, s, expand, stops, banned, c.MaxHops * 2)
+	if errors.Is(err, errRevisits) {
+		keys, err = constrainedChain(ctx, s, expand, stops, banned, c.MaxHops * 2)
+	}
+	if err != nil { return Path{}, err }
+	return linkedHops(ctx, s, keys, srcRes, destRes)
+}
+
+// linkedHops makes a Path of the movies and actors in keys, each hop linked by
+// the actor the search went through between its two movies.
+func linkedHops(ctx context.Context, s Source, keys []int, from, to MovieResource) (Path, error) {
+	path := Path{From: from, To: to, Hops: []Hop{}, Degree: len(keys) / 2}
+	titles := map[int]string{from.Id: from.Title, to.Id: to.Title}
+
+	for i := 2; i < len(keys); i += 2 {
+		first, second, actor := keys[i - 2], keys[i], keyNode(keys[i - 1]).Id
+		films, err := s.GetFilmographyContext(ctx, actor)
+		if err != nil { return Path{}, err }
+		name, err := actorName(ctx, s, actor, first, second)
+		if err != nil { return Path{}, err }
+
+		hop := Hop{From: MovieResource{Id: first}, To: MovieResource{Id: second}}
+		for _, movie := range []*MovieResource{&hop.From, &hop.To} {
+			if titles[movie.Id] == "" {
+				titles[movie.Id] = films[movie.Id].Name
+			}
+			if titles[movie.Id] == "" {
+				res, err := s.GetMovieFromIdContext(ctx, movie.Id)
+				if err != nil { return Path{}, err }
+				titles[movie.Id] = res.Title
+			}
+			movie.Title = titles[movie.Id]
+		}
+		hop.Actors = []Link{{
+			Id:            actor,
+			Name:          name,
+			FromCharacter: films[first].Character,
+			ToCharacter:   films[second].Character,
+		}}
+		path.Hops = append(path.Hops, hop)
+	}
+	return path, nil
+}
+
+// actorName reads an actor's name from the cast of one of their movies, and
+// only looks the actor up when neither cast lists them.
+func actorName(ctx context.Context, s Source, actor int, movies ...int) (string, error) {
+	for _, movie := range movies {
+		cast, err := s.GetCastContext(ctx, movie)
+		if err != nil { return "", err }
+		if role, ok := cast[actor]; ok {
+			return role.Name, nil
+		}
+	}
+	res, err := s.GetActorFromIdContext(ctx, actor)
+	if err != nil { return "", err }
+	return res.Name, nil
+}
+
+// errRevisits is returned by throughWaypoints when each of the shortest walks
+// through the stops passes some movie twice.
+var errRevisits = errors.New("every shortest walk through the stops revisits a movie")
+
+// throughWaypoints finds the first shortest path over the movie and actor
+// graph from stops[0] through the other stops in order that keeps off banned
+// nodes and within maxHops.
+func throughWaypoints(
+	ctx context.Context,
+	s Source,
+	expand expandFunc,
+	stops []int,
+	banned map[int]bool,
+	maxHops int,
+) ([]int, error) {
+	g := waypointGraph{stops: stops, expand: expand}
+	rules := searchRules{banned: map[int]bool{}, maxHops: maxHops}
+	for key := range banned {
+		for layer := range stops {
+			rules.banned[g.state(key, layer)] = true
+		}
+	}
+	last := len(stops) - 1
+	result, err := searchGraph(
+		ctx, s, g.neighbors, g.state(stops[0], 0), g.state(stops[last], last), rules,
+	)
+	if err != nil { return nil, err }
+
+	// a movie can be in more than one layer, but a path only passes it once
+	walks := result.pathsWhere(1, func(path []int, next int) bool {
+		key, _ := g.split(next)
+		return keyNode(key).Kind == MovieKind && slices.ContainsFunc(path, func(state int) bool {
+			k, _ := g.split(state)
+			return k == key
+		})
+	})
+	if len(walks) == 0 {
+		return nil, errRevisits
+	}
+	keys := make([]int, len(walks[0]))
+	for i, state := range walks[0] {
+		keys[i], _ = g.split(state)
+	}
+	return keys, nil
+}
+
+// waypointGraph layers a graph by how many of stops a walk from stops[0] has
+// reached, so that a path through the stops in order is a plain path from
+// stops[0] in layer 0 to the last stop in the last layer. Each stop is only
+// in its own layer, and the last layer holds nothing else.
+type waypointGraph struct {
+	stops  []int
+	expand expandFunc
+}
+
+// state is the key of a node in a layer, as searchGraph sees it.
+func (g waypointGraph) state(key, layer int) int {
+	return key * len(g.stops) + layer
+}
+
+func (g waypointGraph) split(state int) (int, int) {
+	n := len(g.stops)
+	layer := (state % n + n) % n
+	return (state - layer) / n, layer
+}
+
+func (g waypointGraph) inLayer(key, layer int) bool {
+	last := len(g.stops) - 1
+	if layer == last {
+		return key == g.stops[last]
+	}
+	i := slices.Index(g.stops, key)
+	return i < 0 || i == layer
+}
+
+// neighbors is the expandFunc of the layered graph. Stepping onto the next stop
+// moves up a layer and stepping back off a stop moves down one, which keeps
+// the graph undirected for the half of the search going backwards from dest.
+func (g waypointGraph) neighbors(ctx context.Context, state int) (map[int]struct{}, error) {
+	key, layer := g.split(state)
+	keys, err := g.expand(ctx, key)
+	if err != nil { return nil, err }
+
+	out := make(map[int]struct{}, len(keys))
+	for next := range keys {
+		if layer + 1 < len(g.stops) && next == g.stops[layer + 1] {
+			out[g.state(next, layer + 1)] = struct{}{}
+		} else if g.inLayer(next, layer) {
+			out[g.state(next, layer)] = struct{}{}
+		}
+		if layer > 0 && key == g.stops[layer] && g.inLayer(next, layer - 1) {
+			out[g.state(next, layer - 1)] = struct{}{}
+		}
+	}
+	return out, nil
+}
+
+// constrainedChain joins the shortest legs between consecutive stops. A leg
+// may not touch banned nodes, the movies of earlier legs or later stops, and
+// leaves at least a hop of maxHops for every leg after it.
+func constrainedChain(
+	ctx context.Context,
+	s Source,
+	expand expandFunc,
+	stops []int,
+	banned map[int]bool,
+	maxHops int,
+) ([]int, error) {
+	chain := []int{stops[0]}
+	for i := 1; i < len(stops); i++ {
+		rules := searchRules{banned: map[int]bool{}}
+		for key := range banned {
+			rules.banned[key] = true
+		}
+		for _, key := range chain[:len(chain) - 1] {
+			if keyNode(key).Kind == MovieKind {
+				rules.banned[key] = true
+			}
+		}
+		for _, key := range stops[i + 1:] {
+			rules.banned[key] = true
+		}
+		if maxHops > 0 {
+			rules.maxHops = maxHops - (len(chain) - 1) - (len(stops) - 1 - i)
+			if rules.maxHops <= 0 {
+				return nil, ErrNoPath
+			}
+		}
+
+		result, err := searchGraph(ctx, s, expand, stops[i - 1], stops[i], rules)
+		if err != nil { return nil, err }
+		chain = append(chain, result.first[1:]...)
+	}
+	return chain, nil
+}
@@ -0,0 +1,250 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestGetPathConstrained(t *testing.T) {
+	// 1 to 4 through 2 or 3, or the long way through 5 and 6. Actors 20 and 24
+	// both link 2 and 4.
+	source := NewMemorySource()
+	for id := 1; id <= 6; id++ {
+		source.AddMovie(MovieResource{Id: id, Title: "movie"})
+	}
+	for _, credit := range [][2]int{
+		{1, 13}, {2, 13}, {2, 20}, {4, 20}, {2, 24}, {4, 24},
+		{1, 11}, {3, 11}, {3, 21}, {4, 21},
+		{1, 12}, {5, 12}, {5, 22}, {6, 22}, {6, 23}, {4, 23},
+	} {
+		source.AddActor(ActorResource{Id: credit[1], Name: "actor"})
+		source.AddCredit(credit[0], credit[1], "role")
+	}
+
+	tests := []struct{
+		name string
+		c    Constraints
+		want []int
+	}{
+		{"none", Constraints{}, []int{1, 2, 4}},
+		{"one of two links banned", Constraints{BannedActors: []int{20}}, []int{1, 2, 4}},
+		{"both links banned", Constraints{BannedActors: []int{20, 24}}, []int{1, 3, 4}},
+		{"movies banned", Constraints{BannedMovies: []int{2, 3}}, []int{1, 5, 6, 4}},
+		{"via movie", Constraints{Via: []Node{{MovieKind, 3}}}, []int{1, 3, 4}},
+		{"via actor", Constraints{Via: []Node{{ActorKind, 22}}}, []int{1, 5, 6, 4}},
+		{"via one of two links", Constraints{Via: []Node{{ActorKind, 24}}}, []int{1, 2, 4}},
+		{"within hops", Constraints{BannedMovies: []int{2, 3}, MaxHops: 3}, []int{1, 5, 6, 4}},
+		{"too few hops", Constraints{BannedMovies: []int{2, 3}, MaxHops: 2}, nil},
+		{"via too far", Constraints{Via: []Node{{MovieKind, 6}}, MaxHops: 2}, nil},
+		{"via the other way", Constraints{Via: []Node{{MovieKind, 6}, {MovieKind, 5}}}, nil},
+	}
+	for _, test := range tests {
+		path, err := GetPathConstrained(source, "tmdb:1", "tmdb:4", test.c)
+		if test.want == nil {
+			if !errors.Is(err, ErrNoPath) {
+				t.Errorf("%s: got %v, %v, wanted no path", test.name, path.Movies(), err)
+			}
+			continue
+		}
+		if err != nil { t.Fatalf("%s: %v", test.name, err) }
+		if !slices.Equal(path.Movies(), test.want) {
+			t.Errorf("%s: got %v, wanted %v", test.name, path.Movies(), test.want)
+		}
+		for _, hop := range path.Hops {
+			if len(hop.Actors) != 1 {
+				t.Errorf("%s: hop %+v isn't linked by the one actor it went through", test.name, hop)
+			}
+			for _, link := range hop.Actors {
+				if slices.Contains(test.c.BannedActors, link.Id) {
+					t.Errorf("%s: hop %+v links through a banned actor", test.name, hop)
+				}
+			}
+		}
+		for _, via := range test.c.Via {
+			linked := slices.ContainsFunc(path.Hops, func(hop Hop) bool {
+				return slices.ContainsFunc(hop.Actors, func(link Link) bool { return link.Id == via.Id })
+			})
+			if via.Kind == ActorKind && !linked {
+				t.Errorf("%s: no hop links through actor %d: %+v", test.name, via.Id, path.Hops)
+			}
+		}
+	}
+
+	_, err := GetPathConstrained(source, "tmdb:1", "tmdb:4", Constraints{
+		BannedMovies: []int{3}, Via: []Node{{MovieKind, 3}},
+	})
+	if err == nil || errors.Is(err, ErrNoPath) {
+		t.Errorf("expected an error for a banned waypoint, got %v", err)
+	}
+}
+
+func TestWaypointsAreSearchedTogether(t *testing.T) {
+	// 1 reaches 3 through 2 or 4, and 2 is the only way on from 3 to 5. Taking
+	// the first shortest leg to 3, through 2, would leave no way to 5.
+	source := NewMemorySource()
+	for id := 1; id <= 5; id++ {
+		source.AddMovie(MovieResource{Id: id, Title: "movie"})
+	}
+	for _, credit := range [][2]int{
+		{1, 30}, {2, 30}, {2, 31}, {3, 31},
+		{1, 20}, {4, 20}, {4, 21}, {3, 21},
+		{2, 32}, {5, 32},
+	} {
+		source.AddActor(ActorResource{Id: credit[1], Name: "actor"})
+		source.AddCredit(credit[0], credit[1], "role")
+	}
+
+	stops := []int{1, 3, 5}
+	_, err := constrainedChain(context.Background(), source, bipartiteNeighbors(source), stops, nil, 0)
+	if !errors.Is(err, ErrNoPath) {
+		t.Fatalf("the first leg didn't block the second: %v", err)
+	}
+
+	c := Constraints{Via: []Node{{MovieKind, 3}}}
+	path, err := GetPathConstrained(source, "tmdb:1", "tmdb:5", c)
+	if err != nil { t.Fatal(err) }
+	if want := []int{1, 4, 3, 2, 5}; !slices.Equal(path.Movies(), want) {
+		t.Errorf("got %v, wanted %v", path.Movies(), want)
+	}
+	c.MaxHops = 3
+	if _, err := GetPathConstrained(source, "tmdb:1", "tmdb:5", c); !errors.Is(err, ErrNoPath) {
+		t.Errorf("got %v within 3 hops, wanted no path", err)
+	}
+}
+
+// shortestThrough is the length of the shortest path over the movie and actor
+// graph through stops in order that passes no movie twice, of at most maxHops,
+// found by trying every path. It is -1 when there is none.
+func shortestThrough(t *testing.T, expand expandFunc, stops []int, maxHops int) int {
+	t.Helper()
+	best := -1
+	onPath := map[int]bool{}
+	var walk func(node, next, depth int)
+	walk = func(node, next, depth int) {
+		if node == stops[next] {
+			next++
+			if next == len(stops) {
+				if best < 0 || depth < best {
+					best = depth
+				}
+				return
+			}
+		}
+		if depth == maxHops {
+			return
+		}
+		if keyNode(node).Kind == MovieKind {
+			onPath[node] = true
+			defer delete(onPath, node)
+		}
+		neighbors, err := expand(context.Background(), node)
+		if err != nil { t.Fatal(err) }
+		for neighbor := range neighbors {
+			if i := slices.Index(stops, neighbor); i >= 0 && i != next {
+				continue
+			}
+			if !onPath[neighbor] {
+				walk(neighbor, next, depth + 1)
+			}
+		}
+	}
+	walk(stops[0], 1, 0)
+	return best
+}
+
+func TestWaypointsMatchEnumeration(t *testing.T) {
+	const maxHops = 8
+	for seed := int64(1); seed <= 30; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		source := randomSource(r, 10, 10, 25)
+		expand := bipartiteNeighbors(source)
+		for i := 0; i < 5; i++ {
+			stops := []int{1 + r.Intn(10), -1 - r.Intn(10), 1 + r.Intn(10), 1 + r.Intn(10)}
+			if stops[0] == stops[2] || stops[0] == stops[3] || stops[2] == stops[3] {
+				continue
+			}
+			want := shortestThrough(t, expand, stops, maxHops)
+			keys, err := throughWaypoints(context.Background(), source, expand, stops, nil, maxHops)
+			if errors.Is(err, errRevisits) {
+				continue
+			}
+			if want < 0 {
+				if !errors.Is(err, ErrNoPath) {
+					t.Errorf("seed %d, through %v: got %v, %v, wanted no path", seed, stops, keys, err)
+				}
+				continue
+			}
+			if err != nil { t.Fatalf("seed %d, through %v: %v", seed, stops, err) }
+
+			if len(keys) - 1 != want {
+				t.Errorf("seed %d, through %v: got %v, wanted %d hops", seed, stops, keys, want)
+			}
+			next := 0
+			for j, key := range keys {
+				if next < len(stops) && key == stops[next] {
+					next++
+				}
+				if key > 0 && slices.Contains(keys[:j], key) {
+					t.Errorf("seed %d: %v passes movie %d twice", seed, keys, key)
+				}
+				if j > 0 {
+					neighbors, _ := expand(context.Background(), keys[j - 1])
+					if _, ok := neighbors[key]; !ok {
+						t.Errorf("seed %d: %v has no edge to %d", seed, keys, key)
+					}
+				}
+			}
+			if next != len(stops) || keys[len(keys) - 1] != stops[len(stops) - 1] {
+				t.Errorf("seed %d: %v doesn't pass %v in order", seed, keys, stops)
+			}
+		}
+	}
+}
+
+func TestBannedNodesAreNeverExpanded(t *testing.T) {
+	for seed := int64(1); seed <= 30; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		source := randomSource(r, 30, 30, 70)
+		banned := map[int]bool{}
+		for len(banned) < 5 {
+			banned[1 + r.Intn(30)] = true
+		}
+		// the graph with the banned movies taken out
+		without := func(ctx context.Context, id int) (map[int]struct{}, error) {
+			neighbors, err := source.GetNeighborsContext(ctx, id)
+			if err != nil { return nil, err }
+			out := map[int]struct{}{}
+			for neighbor := range neighbors {
+				if !banned[neighbor] {
+					out[neighbor] = struct{}{}
+				}
+			}
+			return out, nil
+		}
+
+		for i := 0; i < 10; i++ {
+			src, dest := 1 + r.Intn(30), 1 + r.Intn(30)
+			if src == dest || banned[src] || banned[dest] {
+				continue
+			}
+			expansions := &expansionLog{nodes: map[int]struct{}{}}
+			result, err := searchGraph(context.Background(), source,
+				expansions.wrap(source.GetNeighborsContext), src, dest,
+				searchRules{banned: banned},
+			)
+			var path []int
+			if err == nil {
+				path = result.first
+			}
+			checkShortest(t, without, path, err, src, dest, plainBFS(t, without, src, dest))
+			for node := range banned {
+				if expansions.expanded(node) {
+					t.Errorf("seed %d: banned %d was expanded", seed, node)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package tmdbapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrUnauthorized = errors.New("tmdb rejected the bearer token")
+	ErrNotFound     = errors.New("tmdb resource not found")
+	ErrRateLimited  = errors.New("tmdb rate limit exceeded")
+	ErrServer       = errors.New("tmdb server error")
+)
+
+// APIError is returned for any non 2xx response from TMDB. It matches
+// ErrUnauthorized, ErrNotFound, ErrRateLimited and ErrServer with errors.Is.
+// TMDBCode and TMDBMessage are the status_code and status_message of TMDB's
+// error payload, when it sent one.
+type APIError struct {
+	StatusCode  int
+	TMDBCode    int
+	TMDBMessage string
+	URL         string
+	RetryAfter  time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("tmdb request %s failed with %d %s",
+		e.URL, e.StatusCode, http.StatusText(e.StatusCode),
+	)
+	if e.TMDBMessage != "" {
+		msg += fmt.Sprintf(": %s (code %d)", e.TMDBMessage, e.TMDBCode)
+	}
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// NotFoundError is returned when a title or name matches nothing on TMDB. It
+// matches ErrNotFound with errors.Is.
+type NotFoundError struct {
+	Kind  Kind
+	Query string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("Could not find \"%s\"", e.Query)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Retryable reports whether the request may succeed if sent again later, which
+// is the case for rate limiting and server errors.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newAPIError describes a failed response, reading TMDB's error payload from
+// body when it has one. Credentials in the query string are left out of URL.
+func newAPIError(response *http.Response, rawURL string, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: response.StatusCode,
+		URL:        redactURL(rawURL),
+		RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+	}
+	var payload struct {
+		StatusCode    int    `json:"status_code"`
+		StatusMessage string `json:"status_message"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		apiErr.TMDBCode = payload.StatusCode
+		apiErr.TMDBMessage = payload.StatusMessage
+	}
+	return apiErr
+}
+
+var secretParams = []string{"api_key", "access_token", "session_id", "guest_session_id"}
+
+// redactURL removes the query parameters TMDB accepts credentials in, so the
+// URL can go in error messages and logs.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil { return rawURL }
+	query := u.Query()
+	redacted := false
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Del(param)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawURL
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,54 @@
+package tmdbapi
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup deduplicates concurrent fetches of the same resource so callers
+// asking for the same key while a fetch is in flight share its result.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	val  any
+	err  error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// do runs fn for key, or waits for the call already in flight for key. A
+// waiting caller gives up when its own ctx is done.
+func (g *flightGroup) do(
+	ctx context.Context,
+	key string,
+	fn func() (any, error),
+) (any, bool, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.val, true, call.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.val, call.err = fn()
+	close(call.done)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return call.val, false, call.err
+}
@@ -0,0 +1,176 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// KShortestPaths finds up to k loopless chains of shared actors between two
+// movies, given like GetPath's, shortest first. Chains of the same length are
+// ordered by the movie ids along them. A maxDepth above zero leaves out chains
+// of more hops than that.
+func KShortestPaths(s Source, src, dest string, k, maxDepth int) ([]Path, error) {
+	return KShortestPathsContext(context.Background(), s, src, dest, k, maxDepth)
+}
+
+func KShortestPathsContext(
+	ctx context.Context,
+	s Source,
+	src, dest string,
+	k, maxDepth int,
+) ([]Path, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be at least 1, got %d", k)
+	}
+	srcRes, err := resolveMovie(ctx, s, src)
+	if err != nil { return nil, err }
+	destRes, err := resolveMovie(ctx, s, dest)
+	if err != nil { return nil, err }
+	if destRes.Id == srcRes.Id {
+		return []Path{{From: srcRes, To: destRes, Hops: []Hop{}}}, nil
+	}
+
+	expansions := &expansionLog{nodes: map[int]struct{}{}}
+	chains, err := kShortest(
+		ctx, s, expansions.wrap(s.GetNeighborsContext),
+		srcRes.Id, destRes.Id, k, maxDepth,
+	)
+	if err != nil { return nil, err }
+
+	paths := make([]Path, len(chains))
+	for i, movies := range chains {
+		paths[i], err = buildHops(ctx, s, movies, srcRes, destRes, func(hop int) bool {
+			return expansions.expanded(movies[hop])
+		})
+		if err != nil { return nil, err }
+	}
+	return paths, nil
+}
+
+// kShortest is Yen's algorithm. Each path after the first is the best of the
+// candidates made by leaving an earlier path at one of its nodes, the spur,
+// and finding the shortest way on to dest that avoids the nodes before the
+// spur and the next hop of every path found so far with the same start.
+func kShortest(
+	ctx context.Context,
+	s Source,
+	expand expandFunc,
+	src, dest int,
+	k, maxDepth int,
+) ([][]int, error) {
+	first, err := shortestAvoiding(ctx, s, expand, src, dest, nil, nil, maxDepth)
+	if err != nil { return nil, err }
+
+	found := [][]int{first}
+	seen := map[string]bool{fmt.Sprint(first): true}
+	candidates := [][]int{}
+	for len(found) < k {
+		prev := found[len(found) - 1]
+		for i := 0; i < len(prev) - 1; i++ {
+			root := prev[:i + 1]
+			nodes := map[int]bool{}
+			for _, node := range root[:i] {
+				nodes[node] = true
+			}
+			edges := map[[2]int]bool{}
+			for _, path := range found {
+				if len(path) > i + 1 && slices.Equal(path[:i + 1], root) {
+					edges[edge(path[i], path[i + 1])] = true
+				}
+			}
+
+			budget := 0
+			if maxDepth > 0 {
+				budget = maxDepth - i
+			}
+			spur, err := shortestAvoiding(ctx, s, expand, prev[i], dest, nodes, edges, budget)
+			if errors.Is(err, ErrNoPath) {
+				continue
+			}
+			if err != nil { return nil, err }
+
+			path := append(slices.Clone(root[:i]), spur...)
+			if key := fmt.Sprint(path); !seen[key] {
+				seen[key] = true
+				candidates = append(candidates, path)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		slices.SortFunc(candidates, comparePaths)
+		found = append(found, candidates[0])
+		candidates = candidates[1:]
+	}
+	return found, nil
+}
+
+// shortestAvoiding is the first shortest path from src to dest that doesn't
+// pass through nodes or along edges.
+func shortestAvoiding(
+	ctx context.Context,
+	s Source,
+	expand expandFunc,
+	src, dest int,
+	nodes map[int]bool,
+	edges map[[2]int]bool,
+	maxHops int,
+) ([]int, error) {
+	avoiding := func(ctx context.Context, id int) (map[int]struct{}, error) {
+		neighbors, err := expand(ctx, id)
+		if err != nil || len(edges) == 0 { return neighbors, err }
+		out := make(map[int]struct{}, len(neighbors))
+		for neighbor := range neighbors {
+			if !edges[edge(id, neighbor)] {
+				out[neighbor] = struct{}{}
+			}
+		}
+		return out, nil
+	}
+	rules := searchRules{banned: nodes, maxHops: maxHops}
+	result, err := searchGraph(ctx, s, avoiding, src, dest, rules)
+	if err != nil { return nil, err }
+	return result.first, nil
+}
+
+// edge is the key of an undirected edge, whichever way round it is given.
+func edge(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// comparePaths orders paths by length, then by the ids along them.
+func comparePaths(a, b []int) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return slices.Compare(a, b)
+}
+
+// expansionLog remembers which nodes an expandFunc was called on, so the hops
+// of a path can be read from the side whose credits are already cached.
+type expansionLog struct {
+	mu    sync.Mutex
+	nodes map[int]struct{}
+}
+
+func (l *expansionLog) wrap(expand expandFunc) expandFunc {
+	return func(ctx context.Context, id int) (map[int]struct{}, error) {
+		l.mu.Lock()
+		l.nodes[id] = struct{}{}
+		l.mu.Unlock()
+		return expand(ctx, id)
+	}
+}
+
+func (l *expansionLog) expanded(id int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.nodes[id]
+	return ok
+}
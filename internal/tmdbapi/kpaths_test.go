@@ -0,0 +1,129 @@
+package tmdbapi
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestKShortestPaths(t *testing.T) {
+	// 1 to 4 through 2 or 3, or the long way through 5 and 6
+	source := NewMemorySource()
+	for id := 1; id <= 6; id++ {
+		source.AddMovie(MovieResource{Id: id, Title: "movie"})
+	}
+	for _, credit := range [][2]int{
+		{1, 10}, {2, 10}, {2, 20}, {4, 20},
+		{1, 11}, {3, 11}, {3, 21}, {4, 21},
+		{1, 12}, {5, 12}, {5, 22}, {6, 22}, {6, 23}, {4, 23},
+	} {
+		source.AddActor(ActorResource{Id: credit[1], Name: "actor"})
+		source.AddCredit(credit[0], credit[1], "role")
+	}
+
+	tests := []struct{
+		k, maxDepth int
+		want        [][]int
+	}{
+		{1, 0, [][]int{{1, 2, 4}}},
+		{5, 0, [][]int{{1, 2, 4}, {1, 3, 4}, {1, 5, 6, 4}}},
+		{5, 2, [][]int{{1, 2, 4}, {1, 3, 4}}},
+	}
+	for _, test := range tests {
+		paths, err := KShortestPaths(source, "tmdb:1", "tmdb:4", test.k, test.maxDepth)
+		if err != nil { t.Fatal(err) }
+		got := [][]int{}
+		for _, path := range paths {
+			got = append(got, path.Movies())
+			if len(path.Hops) != path.Degree || len(path.Hops[0].Actors) == 0 {
+				t.Errorf("path %v wasn't filled in", path)
+			}
+		}
+		if !slices.EqualFunc(got, test.want, slices.Equal[[]int]) {
+			t.Errorf("k %d, depth %d: got %v, wanted %v", test.k, test.maxDepth, got, test.want)
+		}
+	}
+
+	if _, err := KShortestPaths(source, "tmdb:1", "tmdb:4", 0, 0); err == nil {
+		t.Errorf("expected an error for k 0")
+	}
+}
+
+// simplePaths lists the lengths of every loopless path from src to dest of at
+// most maxDepth hops, shortest first.
+func simplePaths(t *testing.T, expand expandFunc, src, dest, maxDepth int) []int {
+	t.Helper()
+	lengths := []int{}
+	onPath := map[int]bool{}
+	var walk func(node, depth int)
+	walk = func(node, depth int) {
+		if node == dest {
+			lengths = append(lengths, depth)
+			return
+		}
+		if depth == maxDepth {
+			return
+		}
+		onPath[node] = true
+		defer delete(onPath, node)
+		neighbors, err := expand(context.Background(), node)
+		if err != nil { t.Fatal(err) }
+		for neighbor := range neighbors {
+			if !onPath[neighbor] {
+				walk(neighbor, depth + 1)
+			}
+		}
+	}
+	walk(src, 0)
+	slices.Sort(lengths)
+	return lengths
+}
+
+func TestKShortestMatchesEnumeration(t *testing.T) {
+	const k, maxDepth = 6, 5
+	for seed := int64(1); seed <= 30; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		source := randomSource(r, 12, 12, 22)
+		expand := source.GetNeighborsContext
+		for i := 0; i < 5; i++ {
+			src, dest := 1 + r.Intn(12), 1 + r.Intn(12)
+			if src == dest {
+				continue
+			}
+			want := simplePaths(t, expand, src, dest, maxDepth)
+			want = want[:min(k, len(want))]
+
+			paths, err := kShortest(context.Background(), source, expand, src, dest, k, maxDepth)
+			if len(want) == 0 {
+				if err == nil {
+					t.Errorf("seed %d, %d to %d: got %v, wanted no path", seed, src, dest, paths)
+				}
+				continue
+			}
+			if err != nil { t.Fatal(err) }
+
+			got := []int{}
+			seen := map[string]bool{}
+			for _, path := range paths {
+				got = append(got, len(path) - 1)
+				checkShortest(t, expand, path, nil, src, dest, len(path) - 1)
+				if seen[fmt.Sprint(path)] {
+					t.Errorf("seed %d: %v found twice", seed, path)
+				}
+				seen[fmt.Sprint(path)] = true
+				visited := map[int]bool{}
+				for _, node := range path {
+					if visited[node] {
+						t.Errorf("seed %d: %v has a loop", seed, path)
+					}
+					visited[node] = true
+				}
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("seed %d, %d to %d: got lengths %v, wanted %v", seed, src, dest, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package tmdbapi
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type MemorySource struct {
+	mu           sync.RWMutex
+	movies       map[int]MovieResource
+	actors       map[int]ActorResource
+	moviesActors map[int]map[int]string
+	actorsMovies map[int]map[int]string
+	maxRoutines  int
+}
+
+func NewMemorySource() *MemorySource {
+	return &MemorySource{
+		movies:       make(map[int]MovieResource),
+		actors:       make(map[int]ActorResource),
+		moviesActors: make(map[int]map[int]string),
+		actorsMovies: make(map[int]map[int]string),
+		maxRoutines:  defaultMaxRoutines,
+	}
+}
+
+func (m *MemorySource) SetMaxRoutines(r int) {
+	m.maxRoutines = r
+}
+
+func (m *MemorySource) MaxRoutines() int {
+	return m.maxRoutines
+}
+
+func (m *MemorySource) AddMovie(movie MovieResource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.movies[movie.Id] = movie
+}
+
+func (m *MemorySource) AddActor(actor ActorResource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actors[actor.Id] = actor
+}
+
+func (m *MemorySource) AddCredit(movieId, actorId int, character string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.moviesActors[movieId]; !ok {
+		m.moviesActors[movieId] = make(map[int]string)
+	}
+	if _, ok := m.actorsMovies[actorId]; !ok {
+		m.actorsMovies[actorId] = make(map[int]string)
+	}
+	m.moviesActors[movieId][actorId] = character
+	m.actorsMovies[actorId][movieId] = character
+}
+
+func (m *MemorySource) GetMovieFromTitleContext(
+	ctx context.Context,
+	movieTitle string,
+) (MovieResource, error) {
+	if err := ctx.Err(); err != nil { return MovieResource{}, err }
+	title, _ := ParseTitle(movieTitle)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	candidates := []MovieResource{}
+	for _, movie := range m.movies {
+		if strings.EqualFold(movie.Title, title) {
+			candidates = append(candidates, movie)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b MovieResource) int { return a.Id - b.Id })
+	return pickMovie(movieTitle, candidates)
+}
+
+func (m *MemorySource) GetMovieFromIdContext(
+	ctx context.Context,
+	movieId int,
+) (MovieResource, error) {
+	if err := ctx.Err(); err != nil { return MovieResource{}, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if movie, ok := m.movies[movieId]; ok {
+		return movie, nil
+	}
+	return NoTitle, nil
+}
+
+func (m *MemorySource) GetMovieFromImdbIdContext(
+	ctx context.Context,
+	imdbId string,
+) (MovieResource, error) {
+	if err := ctx.Err(); err != nil { return MovieResource{}, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, movie := range m.movies {
+		if movie.ImdbId != "" && movie.ImdbId == imdbId {
+			return movie, nil
+		}
+	}
+	return NoTitle, nil
+}
+
+func (m *MemorySource) GetActorFromNameContext(
+	ctx context.Context,
+	actorName string,
+) (ActorResource, error) {
+	if err := ctx.Err(); err != nil { return ActorResource{}, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, actor := range m.actors {
+		if strings.EqualFold(actor.Name, actorName) {
+			return actor, nil
+		}
+	}
+	return NoName, nil
+}
+
+func (m *MemorySource) GetActorFromIdContext(
+	ctx context.Context,
+	actorId int,
+) (ActorResource, error) {
+	if err := ctx.Err(); err != nil { return ActorResource{}, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if actor, ok := m.actors[actorId]; ok {
+		return actor, nil
+	}
+	return NoName, nil
+}
+
+func (m *MemorySource) GetActorsContext(
+	ctx context.Context,
+	movieId int,
+) (map[int]struct{}, error) {
+	if err := ctx.Err(); err != nil { return nil, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return keySet(m.moviesActors[movieId]), nil
+}
+
+func (m *MemorySource) GetMoviesContext(
+	ctx context.Context,
+	actorId int,
+) (map[int]struct{}, error) {
+	if err := ctx.Err(); err != nil { return nil, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return keySet(m.actorsMovies[actorId]), nil
+}
+
+func (m *MemorySource) GetNeighborsContext(
+	ctx context.Context,
+	movieId int,
+) (map[int]struct{}, error) {
+	if err := ctx.Err(); err != nil { return nil, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	neighbors := make(map[int]struct{})
+	for actor := range m.moviesActors[movieId] {
+		for movie := range m.actorsMovies[actor] {
+			neighbors[movie] = struct{}{}
+		}
+	}
+	return neighbors, nil
+}
+
+func (m *MemorySource) GetCastContext(
+	ctx context.Context,
+	movieId int,
+) (map[int]Role, error) {
+	if err := ctx.Err(); err != nil { return nil, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cast := make(map[int]Role)
+	for actor, character := range m.moviesActors[movieId] {
+		cast[actor] = Role{Name: m.actors[actor].Name, Character: character}
+	}
+	return cast, nil
+}
+
+func (m *MemorySource) GetFilmographyContext(
+	ctx context.Context,
+	actorId int,
+) (map[int]Role, error) {
+	if err := ctx.Err(); err != nil { return nil, err }
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	films := make(map[int]Role)
+	for movie, character := range m.actorsMovies[actorId] {
+		films[movie] = Role{Name: m.movies[movie].Title, Character: character}
+	}
+	return films, nil
+}
+
+func keySet(m map[int]string) map[int]struct{} {
+	out := make(map[int]struct{}, len(m))
+	for k := range m {
+		out[k] = struct{}{}
+	}
+	return out
+}
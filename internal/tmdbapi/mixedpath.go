@@ -0,0 +1,146 @@
+package tmdbapi
+
+import (
+	"context"
+	"strings"
+)
+
+type Kind int
+
+const (
+	MovieKind Kind = iota
+	ActorKind
+)
+
+func (k Kind) String() string {
+	if k == ActorKind {
+		return "actor"
+	}
+	return "movie"
+}
+
+// Endpoint is one end of a mixed search, a movie title or a person's name.
+type Endpoint struct {
+	Kind  Kind
+	Query string
+}
+
+func MovieEndpoint(title string) Endpoint {
+	return Endpoint{Kind: MovieKind, Query: title}
+}
+
+func ActorEndpoint(name string) Endpoint {
+	return Endpoint{Kind: ActorKind, Query: name}
+}
+
+// ParseEndpoint reads an endpoint the way the command line and server take
+// them: a name after "actor:" is a person, anything else a movie.
+func ParseEndpoint(s string) Endpoint {
+	if name, ok := strings.CutPrefix(s, "actor:"); ok {
+		return ActorEndpoint(name)
+	}
+	return MovieEndpoint(s)
+}
+
+type Node struct {
+	Kind Kind
+	Id   int
+}
+
+// GetMixedPath searches the bipartite movie/actor graph between two endpoints
+// of either kind. The path alternates movies and actors.
+func GetMixedPath(s Source, src, dest Endpoint) ([]Node, error) {
+	return GetMixedPathContext(context.Background(), s, src, dest)
+}
+
+func GetMixedPathContext(
+	ctx context.Context,
+	s Source,
+	src, dest Endpoint,
+) ([]Node, error) {
+	srcNode, err := ResolveEndpoint(ctx, s, src)
+	if err != nil { return nil, err }
+	destNode, err := ResolveEndpoint(ctx, s, dest)
+	if err != nil { return nil, err }
+	if srcNode == destNode {
+		return []Node{srcNode}, nil
+	}
+
+	keys, err := runParallelSearch(
+		ctx, s, bipartiteNeighbors(s), nodeKey(srcNode), nodeKey(destNode),
+	)
+	if err != nil { return nil, err }
+
+	path := make([]Node, len(keys))
+	for i, key := range keys {
+		path[i] = keyNode(key)
+	}
+	return path, nil
+}
+
+// ResolveEndpoint finds the movie or actor an endpoint names, failing with a
+// *NotFoundError when there is none.
+func ResolveEndpoint(ctx context.Context, s Source, e Endpoint) (Node, error) {
+	if e.Kind == ActorKind {
+		actorRes, err := s.GetActorFromNameContext(ctx, e.Query)
+		if err != nil { return Node{}, err }
+		if actorRes == NoName {
+			return Node{}, actorNotFoundError(e.Query)
+		}
+		return Node{Kind: ActorKind, Id: actorRes.Id}, nil
+	}
+	movieRes, err := resolveMovie(ctx, s, e.Query)
+	if err != nil { return Node{}, err }
+	return Node{Kind: MovieKind, Id: movieRes.Id}, nil
+}
+
+// The search works on int keys, so in the bipartite graph actors are stored
+// as negative ids to keep them apart from movies.
+func nodeKey(n Node) int {
+	if n.Kind == ActorKind {
+		return -n.Id
+	}
+	return n.Id
+}
+
+func keyNode(key int) Node {
+	if key < 0 {
+		return Node{Kind: ActorKind, Id: -key}
+	}
+	return Node{Kind: MovieKind, Id: key}
+}
+
+func bipartiteNeighbors(s Source) expandFunc {
+	return func(ctx context.Context, key int) (map[int]struct{}, error) {
+		node := keyNode(key)
+		neighbors := make(map[int]struct{})
+		if node.Kind == ActorKind {
+			movies, err := s.GetMoviesContext(ctx, node.Id)
+			if err != nil { return nil, err }
+			for movie := range movies {
+				neighbors[movie] = struct{}{}
+			}
+			return neighbors, nil
+		}
+		actors, err := s.GetActorsContext(ctx, node.Id)
+		if err != nil { return nil, err }
+		for actor := range actors {
+			neighbors[-actor] = struct{}{}
+		}
+		return neighbors, nil
+	}
+}
+
+// ActorPathNodes converts a path from GetActorPath to the nodes it alternates
+// between.
+func ActorPathNodes(path []int) []Node {
+	nodes := make([]Node, len(path))
+	for i, id := range path {
+		kind := ActorKind
+		if i % 2 == 1 {
+			kind = MovieKind
+		}
+		nodes[i] = Node{Kind: kind, Id: id}
+	}
+	return nodes
+}
@@ -0,0 +1,82 @@
+package tmdbapi
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestMixedPath(t *testing.T) {
+	movie := func(id int) Node { return Node{Kind: MovieKind, Id: id} }
+	actor := func(id int) Node { return Node{Kind: ActorKind, Id: id} }
+	tests := map[int]struct{
+		src Endpoint
+		dest Endpoint
+		expected []Node
+		text string
+	}{
+		0: {
+			src: MovieEndpoint("Fight Club"),
+			dest: ActorEndpoint("John Malkovich"),
+			expected: []Node{movie(550), actor(819), movie(10220), actor(6949)},
+			text: "Starting from: Fight Club\n" +
+				"Through: Edward Norton\n" +
+				"Connects to: Rounders\n" +
+				"Connects to: John Malkovich\n",
+		},
+		1: {
+			src: ActorEndpoint("Florence Pugh"),
+			dest: MovieEndpoint("Gravity"),
+			expected: []Node{
+				actor(1373737), movie(331482), actor(5064),
+				movie(10315), actor(1461), movie(49047),
+			},
+			text: "Starting from: Florence Pugh\n" +
+				"Connects to: Little Women\n" +
+				"Through: Meryl Streep\n" +
+				"Connects to: Fantastic Mr. Fox\n" +
+				"Through: George Clooney\n" +
+				"Connects to: Gravity\n",
+		},
+		2: {
+			src: MovieEndpoint("Reservoir Dogs"),
+			dest: MovieEndpoint("Reservoir Dogs"),
+			expected: []Node{movie(500)},
+			text: "Starting from: Reservoir Dogs\n",
+		},
+		3: {
+			src: ActorEndpoint("Matt Damon"),
+			dest: ActorEndpoint("Edward Norton"),
+			expected: []Node{actor(1892), movie(10220), actor(819)},
+			text: "Starting from: Matt Damon\n" +
+				"Connects to: Rounders\n" +
+				"Connects to: Edward Norton\n",
+		},
+	}
+	sources := map[string]Source{
+		"memory": newTestSource(t),
+		"client": newTestClient(t),
+	}
+
+	for name, source := range sources {
+		for _, test := range tests {
+			path, err := GetMixedPath(source, test.src, test.dest)
+			if err != nil {
+				t.Errorf("%s: %s, for %v to %v", name, err.Error(), test.src, test.dest)
+				continue
+			}
+			if !slices.Equal(path, test.expected) {
+				t.Errorf("%s: path for %v to %v was %v, wanted %v",
+					name, test.src, test.dest, path, test.expected,
+				)
+			}
+			var text bytes.Buffer
+			if err := FprintPath(&text, source, path); err != nil {
+				t.Errorf("%s: printing %v: %s", name, path, err.Error())
+			}
+			if text.String() != test.text {
+				t.Errorf("%s: printed %v as:\n%s\nwanted:\n%s", name, path, text.String(), test.text)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var imdbId = regexp.MustCompile(`^tt\d+$`)
+
+func validImdbId(id string) bool {
+	return imdbId.MatchString(id)
+}
+
+// resolveMovie finds the movie a query names. Queries are "tmdb:603",
+// "imdb:tt0133093" or a title, and only titles go through search/movie.
+func resolveMovie(ctx context.Context, s Source, query string) (MovieResource, error) {
+	scheme, id, ok := strings.Cut(strings.TrimSpace(query), ":")
+	switch {
+	case ok && strings.EqualFold(scheme, "tmdb"):
+		movieId, err := strconv.Atoi(id)
+		if err != nil || movieId <= 0 {
+			return MovieResource{}, fmt.Errorf("invalid TMDB id %q", id)
+		}
+		movie, err := s.GetMovieFromIdContext(ctx, movieId)
+		if errors.Is(err, ErrNotFound) || (err == nil && movie == NoTitle) {
+			return MovieResource{}, movieNotFoundError(query)
+		}
+		return movie, err
+	case ok && strings.EqualFold(scheme, "imdb"):
+		if !validImdbId(id) {
+			return MovieResource{}, fmt.Errorf("invalid IMDb id %q", id)
+		}
+		movie, err := s.GetMovieFromImdbIdContext(ctx, id)
+		if err != nil { return MovieResource{}, err }
+		if movie == NoTitle {
+			return MovieResource{}, movieNotFoundError(query)
+		}
+		return movie, nil
+	}
+
+	movie, err := s.GetMovieFromTitleContext(ctx, query)
+	if err != nil { return MovieResource{}, err }
+	if movie == NoTitle {
+		return MovieResource{}, movieNotFoundError(query)
+	}
+	return movie, nil
+}
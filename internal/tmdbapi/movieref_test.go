@@ -0,0 +1,55 @@
+package tmdbapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbtest"
+)
+
+func TestPathFromIds(t *testing.T) {
+	server := tmdbtest.NewServer()
+	defer server.Close()
+	client := New("Bearer test-token", time.Second * 5)
+	client.SetBaseURL(server.BaseURL())
+
+	sources := map[string]Source{
+		"memory": newTestSource(t),
+		"client": &client,
+	}
+	for name, source := range sources {
+		path, err := GetPath(source, "tmdb:530385", "imdb:tt1454468")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if path.From.Title != "Midsommar" || path.To.Title != "Gravity" || path.Degree != 3 {
+			t.Errorf("%s: got %+v", name, path)
+		}
+
+		path, err = GetPath(source, "IMDB:tt0110367", "Empire of the Sun")
+		if err != nil || path.From.Id != 9587 || path.Degree != 1 {
+			t.Errorf("%s: got %+v, %v", name, path, err)
+		}
+
+		for _, query := range []string{"tmdb:99999999", "imdb:tt0000001"} {
+			_, err := GetPath(source, query, "Gravity")
+			var notFound *NotFoundError
+			if !errors.As(err, &notFound) || notFound.Query != query {
+				t.Errorf("%s: got %v for %s", name, err, query)
+			}
+		}
+		for _, query := range []string{"tmdb:abc", "tmdb:-4", "imdb:nm0000123", "imdb:tt0110367/credits"} {
+			if _, err := GetPath(source, query, "Gravity"); err == nil || errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: got %v for invalid %s", name, err, query)
+			}
+		}
+	}
+
+	// only "Empire of the Sun" went through search, the failed lookups stop
+	// before the destination is resolved
+	if searches := server.RequestsTo("/search/movie"); searches != 1 {
+		t.Errorf("made %d title searches", searches)
+	}
+}
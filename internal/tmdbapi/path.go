@@ -0,0 +1,156 @@
+package tmdbapi
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// Path is a chain of movies connected by shared actors, with everything
+// needed to show it already filled in.
+type Path struct {
+	From   MovieResource `json:"from"`
+	To     MovieResource `json:"to"`
+	Hops   []Hop         `json:"hops"`
+	Degree int           `json:"degree"`
+}
+
+// Hop is one step of a Path, from a movie to the next through the actors the
+// two share.
+type Hop struct {
+	From   MovieResource `json:"from"`
+	To     MovieResource `json:"to"`
+	Actors []Link        `json:"actors"`
+}
+
+type Link struct {
+	Id            int    `json:"id"`
+	Name          string `json:"name"`
+	FromCharacter string `json:"from_character"`
+	ToCharacter   string `json:"to_character"`
+}
+
+// Movies returns the movie ids along the path in order.
+func (p Path) Movies() []int {
+	movies := []int{p.From.Id}
+	for _, hop := range p.Hops {
+		movies = append(movies, hop.To.Id)
+	}
+	return movies
+}
+
+func (p Path) String() string {
+	b := strings.Builder{}
+	b.WriteString("Starting from: " + p.From.Title + "\n")
+	for _, hop := range p.Hops {
+		names := make([]string, len(hop.Actors))
+		for i, actor := range hop.Actors {
+			names[i] = actor.Name
+		}
+		b.WriteString("Through: " + strings.Join(names, ", ") + "\n")
+		b.WriteString("Connects to: " + hop.To.Title + "\n")
+	}
+	return b.String()
+}
+
+// buildPath fills in a Path for a chain of movie ids that met at meeting.
+// Each hop is read from the movie the search expanded, its cast and the
+// filmographies of those actors, so with a Client it all comes from the cache.
+func buildPath(
+	ctx context.Context,
+	s Source,
+	movies []int,
+	meeting int,
+	from, to MovieResource,
+) (Path, error) {
+	return buildHops(ctx, s, movies, from, to, func(hop int) bool {
+		return hop < meeting
+	})
+}
+
+// buildHops is buildPath for chains that weren't found by a single search.
+// firstExpanded reports whether the search expanded the first movie of a hop
+// rather than the second. It is only a guess at which cast is cached: when
+// that cast, cut to the search factor, links to nothing, the hop is read from
+// the other movie instead.
+func buildHops(
+	ctx context.Context,
+	s Source,
+	movies []int,
+	from, to MovieResource,
+	firstExpanded func(hop int) bool,
+) (Path, error) {
+	path := Path{From: from, To: to, Hops: []Hop{}, Degree: len(movies) - 1}
+	titles := map[int]string{from.Id: from.Title, to.Id: to.Title}
+
+	for i := 1; i < len(movies); i++ {
+		first := firstExpanded(i - 1)
+		links, err := hopLinks(ctx, s, movies[i - 1], movies[i], first, titles)
+		if err != nil { return Path{}, err }
+		if len(links) == 0 {
+			links, err = hopLinks(ctx, s, movies[i - 1], movies[i], !first, titles)
+			if err != nil { return Path{}, err }
+		}
+
+		path.Hops = append(path.Hops, Hop{
+			From:   MovieResource{Id: movies[i - 1]},
+			To:     MovieResource{Id: movies[i]},
+			Actors: links,
+		})
+	}
+
+	for i := range path.Hops {
+		for _, movie := range []*MovieResource{&path.Hops[i].From, &path.Hops[i].To} {
+			if titles[movie.Id] == "" {
+				res, err := s.GetMovieFromIdContext(ctx, movie.Id)
+				if err != nil { return Path{}, err }
+				titles[movie.Id] = res.Title
+			}
+			movie.Title = titles[movie.Id]
+		}
+	}
+	return path, nil
+}
+
+// hopLinks lists the actors linking two movies, read from the cast of the
+// first when fromFirst is set and of the second otherwise, and the
+// filmographies of those actors. It notes the titles it comes across.
+func hopLinks(
+	ctx context.Context,
+	s Source,
+	first, second int,
+	fromFirst bool,
+	titles map[int]string,
+) ([]Link, error) {
+	expanded, other := first, second
+	if !fromFirst {
+		expanded, other = other, expanded
+	}
+	cast, err := s.GetCastContext(ctx, expanded)
+	if err != nil { return nil, err }
+
+	links := []Link{}
+	for actor, role := range cast {
+		films, err := s.GetFilmographyContext(ctx, actor)
+		if err != nil { return nil, err }
+		film, ok := films[other]
+		if !ok {
+			continue
+		}
+		if titles[other] == "" {
+			titles[other] = film.Name
+		}
+		if titles[expanded] == "" {
+			titles[expanded] = films[expanded].Name
+		}
+		link := Link{Id: actor, Name: role.Name}
+		if fromFirst {
+			link.FromCharacter, link.ToCharacter = role.Character, film.Character
+		} else {
+			link.FromCharacter, link.ToCharacter = film.Character, role.Character
+		}
+		links = append(links, link)
+	}
+	slices.SortFunc(links, func(a, b Link) int { return a.Id - b.Id })
+	return links, nil
+}
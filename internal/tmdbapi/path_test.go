@@ -0,0 +1,91 @@
+package tmdbapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbtest"
+)
+
+func TestPathIsFilledInFromSearch(t *testing.T) {
+	server := tmdbtest.NewServer()
+	defer server.Close()
+	newClient := func() *Client {
+		client := New("Bearer test-token", time.Second * 5)
+		client.SetBaseURL(server.BaseURL())
+		return &client
+	}
+
+	ctx := context.Background()
+	client := newClient()
+	src, err := client.GetMovieFromTitleContext(ctx, "Midsommar")
+	if err != nil { t.Fatal(err) }
+	dest, err := client.GetMovieFromTitleContext(ctx, "Gravity")
+	if err != nil { t.Fatal(err) }
+	if _, err := runParallelSearch(ctx, client, client.GetNeighborsContext, src.Id, dest.Id); err != nil {
+		t.Fatal(err)
+	}
+	searchRequests := server.Requests()
+
+	path, err := GetPath(newClient(), "Midsommar", "Gravity")
+	if err != nil { t.Fatal(err) }
+	if extra := server.Requests() - 2*searchRequests; extra != 0 {
+		t.Errorf("building the path took %d more requests", extra)
+	}
+
+	if path.Degree != 3 || len(path.Hops) != path.Degree {
+		t.Fatalf("got degree %d with %d hops, wanted 3", path.Degree, len(path.Hops))
+	}
+	if path.From.Title != "Midsommar" || path.To.Title != "Gravity" {
+		t.Errorf("got endpoints %q and %q", path.From.Title, path.To.Title)
+	}
+	movies := path.Movies()
+	if movies[0] != src.Id || movies[len(movies) - 1] != dest.Id {
+		t.Errorf("got movies %v", movies)
+	}
+	for i, hop := range path.Hops {
+		if hop.From.Title == "" || hop.To.Title == "" {
+			t.Errorf("hop %d is missing a title: %+v", i, hop)
+		}
+		if i > 0 && hop.From != path.Hops[i - 1].To {
+			t.Errorf("hop %d starts at %v, previous ended at %v", i, hop.From, path.Hops[i - 1].To)
+		}
+		if len(hop.Actors) == 0 {
+			t.Errorf("hop %d has no linking actors", i)
+		}
+		for _, link := range hop.Actors {
+			if link.Name == "" || link.FromCharacter == "" || link.ToCharacter == "" {
+				t.Errorf("hop %d has an incomplete link %+v", i, link)
+			}
+		}
+	}
+}
+
+func TestHopsFallBackToTheOtherCast(t *testing.T) {
+	// with one cast member per movie, Pulp Fiction's cast is John Travolta, who
+	// isn't in Reservoir Dogs, while Reservoir Dogs' is Harvey Keitel, who is
+	// in both
+	client := newTestClient(t)
+	client.SetSearchFactor(1)
+	ctx := context.Background()
+	from, err := client.GetMovieFromIdContext(ctx, 680)
+	if err != nil { t.Fatal(err) }
+	to, err := client.GetMovieFromIdContext(ctx, 500)
+	if err != nil { t.Fatal(err) }
+
+	path, err := buildHops(ctx, client, []int{680, 500}, from, to, func(int) bool { return true })
+	if err != nil { t.Fatal(err) }
+	want := Link{Id: 1037, Name: "Harvey Keitel", FromCharacter: "Winston Wolfe", ToCharacter: "Mr. White"}
+	if actors := path.Hops[0].Actors; len(actors) != 1 || actors[0] != want {
+		t.Errorf("got links %+v, wanted %+v", actors, want)
+	}
+}
+
+func TestPathToSameMovie(t *testing.T) {
+	path, err := GetPath(newTestSource(t), "Fight Club", "fight club")
+	if err != nil { t.Fatal(err) }
+	if path.Degree != 0 || len(path.Hops) != 0 || path.From.Id != 550 {
+		t.Errorf("got %+v, wanted an empty path at Fight Club", path)
+	}
+}
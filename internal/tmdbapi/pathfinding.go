@@ -1,10 +1,13 @@
 package tmdbapi
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"slices"
-	"sync"
+	"strings"
 )
 
 var (
@@ -12,166 +15,294 @@ var (
 	ErrAlreadyVisited = errors.New("Already visited this node")
 )
 
-func GetPath(c *Client, src, dest string) ([]int, error) {
+// SearchAbortedError is returned when the context passed to a search is done
+// before a path is found. It wraps the context's error.
+type SearchAbortedError struct {
+	Levels  int
+	Visited int
+	Err     error
+}
+
+func (e *SearchAbortedError) Error() string {
+	return fmt.Sprintf("search stopped after %d levels and %d visited nodes: %s",
+		e.Levels, e.Visited, e.Err.Error(),
+	)
+}
+
+func (e *SearchAbortedError) Unwrap() error {
+	return e.Err
+}
+
+// GetPath finds the shortest chain of shared actors between two movies, each
+// given as a title, "tmdb:<id>" or "imdb:<id>". An endpoint that doesn't exist
+// fails with a *NotFoundError and a failed TMDB request with an *APIError, so
+// ErrNotFound, ErrUnauthorized and ErrServer tell the outcomes apart.
+func GetPath(s Source, src, dest string) (Path, error) {
+	return GetPathContext(context.Background(), s, src, dest)
+}
+
+func GetPathContext(
+	ctx context.Context,
+	s Source,
+	src, dest string,
+) (Path, error) {
 	//fmt.Printf("Finding path from: %s\nTo: %s\n", src, dest)
-	srcRes, err := c.GetMovieFromTitle(src)
-	if err != nil { return nil, err }
-	if srcRes == NoTitle {
-		return nil, movieNotFoundError(src)
-	}
-	destRes, err := c.GetMovieFromTitle(dest)
-	if err != nil { return nil, err }
-	if destRes == NoTitle {
-		return nil, movieNotFoundError(dest)
-	}
+	srcRes, err := resolveMovie(ctx, s, src)
+	if err != nil { return Path{}, err }
+	destRes, err := resolveMovie(ctx, s, dest)
+	if err != nil { return Path{}, err }
 	if destRes.Id == srcRes.Id {
-		return []int{srcRes.Id, srcRes.Id}, nil
+		return Path{From: srcRes, To: destRes, Hops: []Hop{}}, nil
 	}
-	path, err := c.runParallelSearch(srcRes.Id, destRes.Id)
+	movies, meeting, err := searchMeeting(ctx, s, s.GetNeighborsContext, srcRes.Id, destRes.Id)
 	if err != nil {
-		return nil, err
+		return Path{}, err
+	}
+	if len(movies) == 0 {
+		return Path{}, ErrNoPath
 	}
 
-	c.PrintPath(path)
+	return buildPath(ctx, s, movies, meeting, srcRes, destRes)
+}
+
+// expandFunc returns the nodes one step away from id in the graph being
+// searched.
+type expandFunc func(ctx context.Context, id int) (map[int]struct{}, error)
 
-	return path, nil
+func runParallelSearch(
+	ctx context.Context,
+	s Source,
+	expand expandFunc,
+	src, dest int,
+) ([]int, error) {
+	path, _, err := searchMeeting(ctx, s, expand, src, dest)
+	return path, err
 }
 
-func (c *Client) runParallelSearch(src, dest int) ([]int, error) {
-	srcCurrentLevel, destCurrentLevel := []int{src}, []int{dest}
-	srcNextLevel, destNextLevel := []int{}, []int{}
-	found := []int{}
-	var err error
-	srcVisited, destVisited := sync.Map{}, sync.Map{}
-	srcPredecessors, destPredecessors := sync.Map{}, sync.Map{}
-	srcVisited.Store(src, struct{}{})
-	destVisited.Store(dest, struct{}{})
-	srcPredecessors.Store(src, 0)
-	destPredecessors.Store(dest, 0)
+// searchMeeting is runParallelSearch that also returns the index in the path
+// where the two searches met. Nodes before it were expanded by the search from
+// src and nodes after it by the search from dest, so each hop before the
+// meeting was found from its first node and each hop after from its second.
+func searchMeeting(
+	ctx context.Context,
+	s Source,
+	expand expandFunc,
+	src, dest int,
+) ([]int, int, error) {
+	result, err := searchGraph(ctx, s, expand, src, dest, searchRules{})
+	if err != nil { return nil, 0, err }
+	return result.first, result.meetingDepth(), nil
+}
 
-	for {
-		srcNextLevel, srcCurrentLevel, found, err = c.getNextLevel(
-			srcCurrentLevel, srcNextLevel,
-			&srcVisited, &destVisited, &srcPredecessors,
-		)
-		if err != nil { return nil, err }
-		if len(found) > 0 {
-			break
-		}
-		destNextLevel, destCurrentLevel, found, err = c.getNextLevel(
-			destCurrentLevel, destNextLevel,
-			&destVisited, &srcVisited, &destPredecessors,
-		)
-		if err != nil { return nil, err }		
-		if len(found) > 0 {
-			break
+// searchSide is one direction of the bidirectional search.
+type searchSide struct {
+	name     string
+	root     int
+	level    int
+	frontier []int
+	depth    map[int]int
+	// parents holds, for each node reached, every node one level closer to
+	// the root that it was reached from
+	parents  map[int][]int
+}
+
+func newSearchSide(name string, root int) *searchSide {
+	return &searchSide{
+		name:     name,
+		root:     root,
+		frontier: []int{root},
+		depth:    map[int]int{root: 0},
+		parents:  map[int][]int{},
+	}
+}
+
+// searchResult is a finished search. Every shortest path from src to dest runs
+// through one of meetings, which are sorted, and first is the lowest of them in
+// lexicographic order.
+type searchResult struct {
+	src, dest *searchSide
+	meetings  []int
+	first     []int
+}
+
+// meetingDepth is the index of the meeting node in each shortest path.
+func (r *searchResult) meetingDepth() int {
+	return r.src.depth[r.meetings[0]]
+}
+
+// searchGraph runs a breadth first search from both ends, expanding a whole
+// level of whichever side has the smaller frontier at each step. The first
+// level to reach the other side's nodes holds every shortest path: a shorter
+// one would have met the other side a level earlier. Nodes that break rules
+// are never added to a level, and the search gives up with ErrNoPath once no
+// path within rules.maxHops is left.
+func searchGraph(
+	ctx context.Context,
+	s Source,
+	expand expandFunc,
+	src, dest int,
+	rules searchRules,
+) (*searchResult, error) {
+	srcSide, destSide := newSearchSide("src", src), newSearchSide("dest", dest)
+	visited := 2
+	levels := 0
+	aborted := func() error {
+		return &SearchAbortedError{
+			Levels:  levels,
+			Visited: visited,
+			Err:     ctx.Err(),
 		}
 	}
+	progress := progressFrom(ctx)
+	report := func(event ProgressEvent, side string, meeting int) {
+		progress(Progress{
+			Event:        event,
+			Level:        levels,
+			Side:         side,
+			SrcFrontier:  len(srcSide.frontier),
+			DestFrontier: len(destSide.frontier),
+			Visited:      visited,
+			APICalls:     apiCalls(s),
+			Meeting:      meeting,
+		})
+	}
+	pool := newExpandPool(ctx, expand, maxRoutines(s))
+	defer pool.close()
 
-	finalPath := []int{}
+	for {
+		side, other := srcSide, destSide
+		if len(destSide.frontier) < len(srcSide.frontier) {
+			side, other = destSide, srcSide
+		}
+		// with one-way neighbors the other side can still reach a node this
+		// side has, so there's only no path once both are done
+		if len(side.frontier) == 0 {
+			side, other = other, side
+		}
+		if len(side.frontier) == 0 {
+			return nil, ErrNoPath
+		}
+		if rules.maxHops > 0 && srcSide.level + destSide.level >= rules.maxHops {
+			return nil, ErrNoPath
+		}
+		found, err := getNextLevel(ctx, pool, side, other, rules, &visited)
+		if ctx.Err() != nil { return nil, aborted() }
+		if err != nil { return nil, err }
+		levels++
+		report(ProgressLevel, side.name, 0)
 
-	for _, node := range found {
-		srcPath := pathFromPredecessors(&srcPredecessors, node)
-		destPath := pathFromPredecessors(&destPredecessors, node)
-		srcPath = srcPath[1:]
-		slices.Reverse[[]int](srcPath)
-		srcPath = append(srcPath, destPath...)
-		if len(srcPath) < len(finalPath) || len(finalPath) == 0 {
-			if !slices.Contains[[]int](srcPath, src) || !slices.Contains[[]int](srcPath, dest) {
-				continue
-			}
-			finalPath = srcPath
+		if len(found) > 0 {
+			result := &searchResult{src: srcSide, dest: destSide, meetings: found}
+			result.first = result.paths(1)[0]
+			report(ProgressMeeting, side.name, result.first[result.meetingDepth()])
+			return result, nil
 		}
 	}
+}
 
-	return finalPath, nil
+type levelResult struct {
+	next  []int
+	found map[int]struct{}
 }
 
-func (c *Client) getNextLevel(
-	currentLevel, nextLevel []int,
-	srcVisited, destVisited, predecessors *sync.Map,
-) (cLevel[]int, nLevel[]int, found []int, finalErr error) {
-	errCh := make(chan error)
-	foundCh := make(chan int)
-	queueCh := make(chan int)
-	defer close(errCh)
-	defer close(foundCh)
-	defer close(queueCh)
+// getNextLevel expands the whole frontier of side on the pool, making the
+// nodes it reaches the new frontier. It returns the sorted nodes of the new
+// level that the other side reached in the fewest steps. With one-way
+// neighbors the other side can have reached them at different depths, and
+// only the nearest are on the shortest paths.
+func getNextLevel(
+	ctx context.Context,
+	pool *expandPool,
+	side, other *searchSide,
+	rules searchRules,
+	visited *int,
+) ([]int, error) {
+	result := levelResult{next: []int{}, found: map[int]struct{}{}}
+	_, err := pool.expandLevel(ctx, side.frontier,
+		func(current int, neighbors map[int]struct{}) bool {
+			visitNeighbors(current, neighbors, side, other, rules, &result, visited)
+			return false
+		},
+	)
+	if err != nil { return nil, err }
+	side.frontier = result.next
+	side.level++
 
-	go func() {
-		for e := range errCh {
-			finalErr = e
+	found := make([]int, 0, len(result.found))
+	nearest := -1
+	for node := range result.found {
+		d := other.depth[node]
+		if nearest < 0 || d < nearest {
+			nearest, found = d, found[:0]
 		}
-	}()
-	go func() {
-		for node := range foundCh {
+		if d == nearest {
 			found = append(found, node)
 		}
-	}()
-	go func() {
-		for node := range queueCh {
-			nextLevel = append(nextLevel, node)
-		}
-	}()
-
-	for len(found) == 0 && len(currentLevel) > 0 {
-		nextGroupSize := min(len(currentLevel), c.maxRoutines)
-		searchGroup := currentLevel[:nextGroupSize]
-		currentLevel = currentLevel[nextGroupSize:]
-		wg := sync.WaitGroup{}
-		
-		for i := range searchGroup {
-			current := searchGroup[i]
-			wg.Add(1)
-			go c.visitNeighbors(
-				&wg,
-				current,
-				errCh,
-				foundCh, queueCh,
-				srcVisited, destVisited, predecessors,
-			)
-		}
-		wg.Wait()
-	}
-	return currentLevel, nextLevel, found, finalErr
-}
-
-func (c *Client) visitNeighbors(
-	wg *sync.WaitGroup,
+	}
+	slices.Sort(found)
+	return found, nil
+}
+
+// visitNeighbors records the neighbors of current that side hasn't reached
+// yet, adds current as a parent of those it reached earlier in this level and
+// notes any the other side has reached. Banned neighbors are skipped, as are
+// new ones too far from the other side for a path within the hop limit. It
+// only runs on the goroutine driving the search.
+func visitNeighbors(
 	current int,
-	errCh chan<- error,
-	foundCh, queueCh chan<- int,
-	srcVisited, destVisited, predecessors *sync.Map,
+	neighbors map[int]struct{},
+	side, other *searchSide,
+	rules searchRules,
+	result *levelResult,
+	visited *int,
 ) {
-	defer wg.Done()
-	neighbors, err := c.GetNeighbors(current)
-	if err != nil {
-		errCh <- err
-		foundCh <- -1
-		return
-	}
+	depth := side.depth[current] + 1
 	for neighbor := range neighbors {
-		if _, ok := srcVisited.LoadOrStore(neighbor, struct{}{}); !ok {
-			predecessors.Store(neighbor, current)
-			queueCh <- neighbor
+		if rules.banned[neighbor] {
+			continue
 		}
-		if _, ok := destVisited.Load(neighbor); ok {
-			foundCh <- neighbor
+		d, ok := side.depth[neighbor]
+		_, met := other.depth[neighbor]
+		// the other side hasn't reached neighbor, so it's at least a level
+		// past the other frontier
+		if !ok && !met && rules.maxHops > 0 && depth + other.level + 1 > rules.maxHops {
+			continue
+		}
+		switch {
+		case !ok:
+			side.depth[neighbor] = depth
+			side.parents[neighbor] = []int{current}
+			*visited++
+			result.next = append(result.next, neighbor)
+		case d == depth:
+			side.parents[neighbor] = append(side.parents[neighbor], current)
+		default:
+			continue
+		}
+		if met {
+			result.found[neighbor] = struct{}{}
 		}
 	}
 }
 
 func (c *Client) GetNeighbors(movieId int) (map[int]struct{}, error) {
+	return c.GetNeighborsContext(context.Background(), movieId)
+}
+
+func (c *Client) GetNeighborsContext(
+	ctx context.Context,
+	movieId int,
+) (map[int]struct{}, error) {
 	neighbors, ok := c.cache.GetNeighbors(movieId)
 	if ok {
 		return neighbors, nil
 	}
 
-	actors, err := c.GetActors(movieId)
+	actors, err := c.GetActorsContext(ctx, movieId)
 	if err != nil { return nil, err }
 
 	for actor := range actors {
-		movies, err := c.GetMovies(actor)
+		movies, err := c.GetMoviesContext(ctx, actor)
 		if err != nil { return nil, err }
 
 		for movie := range movies {
@@ -205,45 +336,88 @@ func (c *Client) getConnection(left, right int) (int, error) {
 	return 0, errors.New("Failure finding neighbor connection")
 }
 
-func (c *Client) PrintPath(path []int) error {
-	titles := make([]string, len(path))
-	for i, p := range path {
-		movieRes, err := c.GetMovieFromId(p)
+func PrintPath(s Source, path []Node) error {
+	return FprintPath(os.Stdout, s, path)
+}
+
+// FprintPath writes a chain of nodes to w as text, naming the actors that
+// link each pair of movies.
+func FprintPath(w io.Writer, s Source, path []Node) error {
+	if len(path) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	names := make([]string, len(path))
+	for i, node := range path {
+		name, err := NodeName(ctx, s, node)
 		if err != nil { return err }
-		titles[i] = movieRes.Title
+		names[i] = name
 	}
-	fmt.Printf("Starting from: %s\n", titles[0])
+	fmt.Fprintf(w, "Starting from: %s\n", names[0])
 
-	for i, p := range path {
+	for i, node := range path {
 		if i == 0 {
 			continue
 		}
-		fmt.Printf("Through: ")
-		actors, err := c.OverlappingActors(path[i - 1], p)
-		if err != nil { return err }
-		for _, actor := range actors {
-			actorRes, err := c.GetActorFromId(actor)
+		prev := path[i - 1]
+		if prev.Kind == MovieKind && node.Kind == MovieKind {
+			actors, err := overlappingActors(ctx, s, prev.Id, node.Id)
 			if err != nil { return err }
-			fmt.Printf("%s,", actorRes.Name)			
+			actorNames := make([]string, len(actors))
+			for j, actor := range actors {
+				actorRes, err := s.GetActorFromIdContext(ctx, actor)
+				if err != nil { return err }
+				actorNames[j] = actorRes.Name
+			}
+			fmt.Fprintf(w, "Through: %s\n", strings.Join(actorNames, ", "))
+		}
+		if node.Kind == ActorKind && i < len(path) - 1 {
+			fmt.Fprintf(w, "Through: %s\n", names[i])
+			continue
 		}
-		fmt.Printf("\nConnects to: %s\n", titles[i])
+		fmt.Fprintf(w, "Connects to: %s\n", names[i])
 	}
 	return nil
 }
 
-func pathFromPredecessors(predecessors *sync.Map, src int) []int {
-	path := []int{src}
-	for {
-		next, ok := predecessors.Load(src)
-		if !ok || next == 0 {
-			break
+// NodeName returns the title of a movie node or the name of an actor node.
+func NodeName(ctx context.Context, s Source, node Node) (string, error) {
+	if node.Kind == ActorKind {
+		actorRes, err := s.GetActorFromIdContext(ctx, node.Id)
+		return actorRes.Name, err
+	}
+	movieRes, err := s.GetMovieFromIdContext(ctx, node.Id)
+	return movieRes.Title, err
+}
+
+func overlappingActors(
+	ctx context.Context,
+	s Source,
+	left, right int,
+) ([]int, error) {
+	actorsLeft, err := s.GetActorsContext(ctx, left)
+	if err != nil { return nil, err }
+	actorsRight, err := s.GetActorsContext(ctx, right)
+	if err != nil { return nil, err }
+
+	if len(actorsLeft) > len(actorsRight) {
+		actorsLeft, actorsRight = actorsRight, actorsLeft
+	}
+
+	out := []int{}
+	for actor := range actorsLeft {
+		if _, ok := actorsRight[actor]; ok {
+			out = append(out, actor)
 		}
-		src = next.(int)
-		path = append(path, src)
 	}
-	return path
+	slices.Sort(out)
+	return out, nil
+}
+
+func actorNotFoundError(name string) error {
+	return &NotFoundError{Kind: ActorKind, Query: name}
 }
 
 func movieNotFoundError(title string) error {
-	return errors.New(fmt.Sprintf("Could not find \"%s\"", title))
+	return &NotFoundError{Kind: MovieKind, Query: title}
 }
@@ -0,0 +1,105 @@
+package tmdbapi
+
+import (
+	"context"
+	"sync"
+)
+
+// expandPool runs expansions on a fixed set of workers for the length of a
+// search. Nodes go in on a queue and their neighbors come back on a channel,
+// so only the goroutine running the search touches its bookkeeping and a slow
+// fetch holds up one worker instead of a whole batch.
+type expandPool struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	jobs    chan int
+	results chan expansion
+	wg      sync.WaitGroup
+}
+
+type expansion struct {
+	node      int
+	neighbors map[int]struct{}
+	err       error
+}
+
+func newExpandPool(ctx context.Context, expand expandFunc, workers int) *expandPool {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &expandPool{
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(chan int),
+		results: make(chan expansion),
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work(expand)
+	}
+	return p
+}
+
+func (p *expandPool) work(expand expandFunc) {
+	defer p.wg.Done()
+	for node := range p.jobs {
+		neighbors, err := expand(p.ctx, node)
+		p.results <- expansion{node: node, neighbors: neighbors, err: err}
+	}
+}
+
+// expandLevel sends the nodes of level to the workers and passes each result
+// to visit, one at a time. It stops queueing once visit returns true, the
+// first expansion fails or ctx is done, waits for the expansions already
+// running and returns the nodes it never queued. A failure cancels the
+// expansions still running.
+func (p *expandPool) expandLevel(
+	ctx context.Context,
+	level []int,
+	visit func(node int, neighbors map[int]struct{}) bool,
+) ([]int, error) {
+	var firstErr error
+	stop := false
+	running := 0
+	done := ctx.Done()
+	for running > 0 || (!stop && len(level) > 0) {
+		jobs := p.jobs
+		next := 0
+		if stop || len(level) == 0 {
+			jobs = nil
+		} else {
+			next = level[0]
+		}
+
+		select {
+		case jobs <- next:
+			level = level[1:]
+			running++
+		case res := <-p.results:
+			running--
+			if res.err != nil {
+				if firstErr == nil {
+					firstErr = res.err
+					p.cancel()
+				}
+				stop = true
+				continue
+			}
+			if firstErr == nil && visit(res.node, res.neighbors) {
+				stop = true
+			}
+		case <-done:
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			stop = true
+			done = nil
+		}
+	}
+	return level, firstErr
+}
+
+// close stops the workers once they have finished.
+func (p *expandPool) close() {
+	p.cancel()
+	close(p.jobs)
+	p.wg.Wait()
+}
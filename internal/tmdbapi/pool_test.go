@@ -0,0 +1,122 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolKeepsWorkersBusy(t *testing.T) {
+	others := make(chan struct{})
+	visitedOthers := 0
+	var running, most atomic.Int64
+	expand := func(ctx context.Context, node int) (map[int]struct{}, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		if node == 1 {
+			// blocks until every other node has been expanded, which never
+			// happens if the pool waits for node 1 before queueing more
+			select {
+			case <-others:
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("slow node held up the level")
+			}
+		}
+		return map[int]struct{}{node * 10: {}}, nil
+	}
+
+	pool := newExpandPool(context.Background(), expand, 3)
+	defer pool.close()
+
+	level := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	remaining, err := pool.expandLevel(context.Background(), level,
+		func(node int, neighbors map[int]struct{}) bool {
+			if node != 1 {
+				visitedOthers++
+				if visitedOthers == len(level) - 1 {
+					close(others)
+				}
+			}
+			return false
+		},
+	)
+	if err != nil { t.Fatal(err) }
+	if len(remaining) != 0 {
+		t.Errorf("left %v unexpanded", remaining)
+	}
+	if most.Load() > 3 {
+		t.Errorf("%d expansions ran at once with 3 workers", most.Load())
+	}
+}
+
+func TestPoolFirstErrorCancels(t *testing.T) {
+	failure := errors.New("credits fetch failed")
+	canceled := make(chan struct{})
+	expand := func(ctx context.Context, node int) (map[int]struct{}, error) {
+		if node == 1 {
+			return nil, failure
+		}
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}
+
+	pool := newExpandPool(context.Background(), expand, 2)
+	defer pool.close()
+
+	remaining, err := pool.expandLevel(context.Background(), []int{2, 1, 3, 4},
+		func(int, map[int]struct{}) bool {
+			t.Errorf("visited a failed level")
+			return false
+		},
+	)
+	if !errors.Is(err, failure) {
+		t.Errorf("got error %v, wanted the first failure", err)
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Errorf("running expansion wasn't canceled")
+	}
+	if len(remaining) != 2 {
+		t.Errorf("got remaining %v, wanted the two nodes never queued", remaining)
+	}
+}
+
+func TestPoolStopsQueueing(t *testing.T) {
+	expand := func(ctx context.Context, node int) (map[int]struct{}, error) {
+		return map[int]struct{}{}, nil
+	}
+	pool := newExpandPool(context.Background(), expand, 1)
+	defer pool.close()
+
+	remaining, err := pool.expandLevel(context.Background(), []int{1, 2, 3},
+		func(node int, _ map[int]struct{}) bool { return node == 1 },
+	)
+	if err != nil { t.Fatal(err) }
+	if len(remaining) == 0 || remaining[len(remaining) - 1] != 3 {
+		t.Errorf("got remaining %v after stopping at node 1", remaining)
+	}
+}
+
+func TestParallelSearchIsRaceFree(t *testing.T) {
+	source := newTestSource(t)
+	for _, routines := range []int{1, 2, 7, 64} {
+		source.SetMaxRoutines(routines)
+		for i := 0; i < 20; i++ {
+			path, err := GetPath(source, "Kickboxer", "Dirty Rotten Scoundrels")
+			if err != nil { t.Fatal(err) }
+			if path.Degree != 3 {
+				t.Fatalf("maxroutines %d: got degree %d, wanted 3", routines, path.Degree)
+			}
+		}
+	}
+}
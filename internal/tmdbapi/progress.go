@@ -0,0 +1,53 @@
+package tmdbapi
+
+import "context"
+
+type ProgressEvent string
+
+const (
+	// ProgressLevel is sent after a side of the search expands a level.
+	ProgressLevel ProgressEvent = "level"
+	// ProgressMeeting is sent once when the two sides meet.
+	ProgressMeeting ProgressEvent = "meeting"
+)
+
+// Progress reports how far a search has got. Side is the side that just
+// expanded, "src" or "dest". APICalls is only counted for sources that keep
+// Stats, like Client. Meeting is the node the sides met at, in the id space of
+// the search, and is only set on ProgressMeeting.
+type Progress struct {
+	Event        ProgressEvent `json:"event"`
+	Level        int           `json:"level"`
+	Side         string        `json:"side"`
+	SrcFrontier  int           `json:"src_frontier"`
+	DestFrontier int           `json:"dest_frontier"`
+	Visited      int           `json:"visited"`
+	APICalls     int64         `json:"api_calls"`
+	Meeting      int           `json:"meeting,omitempty"`
+}
+
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context that makes searches run with it call fn as
+// they go. fn is called from the goroutine running the search, one event at a
+// time, so it should return quickly.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFrom(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	if fn == nil {
+		return func(Progress) {}
+	}
+	return fn
+}
+
+func apiCalls(s Source) int64 {
+	if stats, ok := s.(interface{ Stats() Stats }); ok {
+		return stats.Stats().APICalls
+	}
+	return 0
+}
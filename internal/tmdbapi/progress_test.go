@@ -0,0 +1,52 @@
+package tmdbapi
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSearchReportsProgress(t *testing.T) {
+	client := newTestClient(t)
+	events := []Progress{}
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		events = append(events, p)
+	})
+
+	path, err := GetPathContext(ctx, client, "Kickboxer", "Dirty Rotten Scoundrels")
+	if err != nil { t.Fatal(err) }
+	if len(events) < 2 {
+		t.Fatalf("got events %+v", events)
+	}
+
+	srcFrontier, destFrontier := 1, 1
+	for i, event := range events[:len(events) - 1] {
+		if event.Event != ProgressLevel || event.Level != i + 1 {
+			t.Errorf("event %d is %+v", i, event)
+		}
+		wantSide := "src"
+		if destFrontier < srcFrontier {
+			wantSide = "dest"
+		}
+		if event.Side != wantSide {
+			t.Errorf("event %d expanded %s, wanted the smaller frontier %s", i, event.Side, wantSide)
+		}
+		srcFrontier, destFrontier = event.SrcFrontier, event.DestFrontier
+		if i > 0 && (event.APICalls < events[i - 1].APICalls || event.Visited < events[i - 1].Visited) {
+			t.Errorf("event %d went backwards: %+v after %+v", i, event, events[i - 1])
+		}
+	}
+	last := events[len(events) - 1]
+	if last.Event != ProgressMeeting || !slices.Contains(path.Movies(), last.Meeting) {
+		t.Errorf("last event %+v doesn't meet on %v", last, path.Movies())
+	}
+	if last.APICalls == 0 {
+		t.Errorf("client API calls weren't counted")
+	}
+}
+
+func TestNoProgressWithoutCallback(t *testing.T) {
+	if _, err := GetPath(newTestSource(t), "Midsommar", "Gravity"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,209 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// randomSource builds a random credits graph of movies and actors, sparse
+// enough that some pairs aren't connected.
+func randomSource(r *rand.Rand, movies, actors, credits int) *MemorySource {
+	source := NewMemorySource()
+	source.SetMaxRoutines(1 + r.Intn(8))
+	for id := 1; id <= movies; id++ {
+		source.AddMovie(MovieResource{Id: id, Title: "movie " + strconv.Itoa(id)})
+	}
+	for id := 1; id <= actors; id++ {
+		source.AddActor(ActorResource{Id: id, Name: "actor " + strconv.Itoa(id)})
+	}
+	for i := 0; i < credits; i++ {
+		source.AddCredit(1 + r.Intn(movies), 1 + r.Intn(actors), "role")
+	}
+	return source
+}
+
+// oneWayGraph is a random graph over nodes 1 to nodes whose edges only go one
+// way, like Client.GetNeighbors once the searchfactor cuts casts short.
+func oneWayGraph(r *rand.Rand, nodes, edges int) expandFunc {
+	out := map[int]map[int]struct{}{}
+	for id := 1; id <= nodes; id++ {
+		out[id] = map[int]struct{}{}
+	}
+	for i := 0; i < edges; i++ {
+		from, to := 1 + r.Intn(nodes), 1 + r.Intn(nodes)
+		if from != to {
+			out[from][to] = struct{}{}
+		}
+	}
+	return func(ctx context.Context, id int) (map[int]struct{}, error) {
+		return out[id], nil
+	}
+}
+
+// plainBFS returns the number of hops from src to dest, or -1 when there is
+// no path.
+func plainBFS(t *testing.T, expand expandFunc, src, dest int) int {
+	t.Helper()
+	depth := map[int]int{src: 0}
+	queue := []int{src}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node == dest {
+			return depth[node]
+		}
+		neighbors, err := expand(context.Background(), node)
+		if err != nil { t.Fatal(err) }
+		for neighbor := range neighbors {
+			if _, ok := depth[neighbor]; !ok {
+				depth[neighbor] = depth[node] + 1
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return -1
+}
+
+func checkShortest(t *testing.T, expand expandFunc, path []int, err error, src, dest, want int) {
+	t.Helper()
+	if want < 0 {
+		if !errors.Is(err, ErrNoPath) {
+			t.Errorf("%d to %d: got %v, %v, wanted no path", src, dest, path, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("%d to %d: got error %v, wanted %d hops", src, dest, err, want)
+		return
+	}
+	if len(path) - 1 != want || path[0] != src || path[len(path) - 1] != dest {
+		t.Errorf("%d to %d: got %v, wanted %d hops", src, dest, path, want)
+		return
+	}
+	for i := 1; i < len(path); i++ {
+		neighbors, _ := expand(context.Background(), path[i - 1])
+		if _, ok := neighbors[path[i]]; !ok {
+			t.Errorf("%d to %d: %v has no edge %d to %d", src, dest, path, path[i - 1], path[i])
+		}
+	}
+}
+
+func TestSearchMatchesPlainBFS(t *testing.T) {
+	for seed := int64(1); seed <= 40; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		movies, actors := 10 + r.Intn(60), 10 + r.Intn(60)
+		source := randomSource(r, movies, actors, movies + r.Intn(2 * movies))
+		graphs := map[string]expandFunc{
+			"movies": source.GetNeighborsContext,
+			"actors": coStars(source),
+		}
+		for name, expand := range graphs {
+			for i := 0; i < 10; i++ {
+				src, dest := 1 + r.Intn(min(movies, actors)), 1 + r.Intn(min(movies, actors))
+				if src == dest {
+					continue
+				}
+				want := plainBFS(t, expand, src, dest)
+				path, err := runParallelSearch(context.Background(), source, expand, src, dest)
+				checkShortest(t, expand, path, err, src, dest, want)
+				if t.Failed() {
+					t.Fatalf("seed %d, %s graph", seed, name)
+				}
+			}
+		}
+	}
+}
+
+func TestSearchIsDeterministic(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	source := randomSource(r, 80, 80, 200)
+	source.SetMaxRoutines(16)
+	src, dest, farthest := 0, 0, 0
+	for i := 1; i <= 80; i++ {
+		for j := i + 1; j <= 80 && farthest < 3; j++ {
+			if hops := plainBFS(t, source.GetNeighborsContext, i, j); hops > farthest {
+				src, dest, farthest = i, j, hops
+			}
+		}
+	}
+	if farthest < 2 {
+		t.Fatalf("random graph has no pair two hops apart")
+	}
+
+	first, err := runParallelSearch(context.Background(), source, source.GetNeighborsContext, src, dest)
+	if err != nil { t.Fatal(err) }
+	for i := 0; i < 20; i++ {
+		path, err := runParallelSearch(context.Background(), source, source.GetNeighborsContext, src, dest)
+		if err != nil { t.Fatal(err) }
+		if !slices.Equal(path, first) {
+			t.Fatalf("got %v, then %v", first, path)
+		}
+	}
+}
+
+// reachedByBoth returns a node that following expand reaches from both src
+// and dest, if there is one.
+func reachedByBoth(t *testing.T, expand expandFunc, src, dest int) (int, bool) {
+	t.Helper()
+	reached := func(root int) map[int]bool {
+		seen := map[int]bool{root: true}
+		queue := []int{root}
+		for len(queue) > 0 {
+			neighbors, err := expand(context.Background(), queue[0])
+			if err != nil { t.Fatal(err) }
+			queue = queue[1:]
+			for neighbor := range neighbors {
+				if !seen[neighbor] {
+					seen[neighbor] = true
+					queue = append(queue, neighbor)
+				}
+			}
+		}
+		return seen
+	}
+	fromDest := reached(dest)
+	for node := range reached(src) {
+		if fromDest[node] {
+			return node, true
+		}
+	}
+	return 0, false
+}
+
+func TestSearchOverOneWayNeighborsJoinsNearestMeeting(t *testing.T) {
+	for seed := int64(1); seed <= 40; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		expand := oneWayGraph(r, 30, 60)
+		for i := 0; i < 10; i++ {
+			src, dest := 1 + r.Intn(30), 1 + r.Intn(30)
+			if src == dest {
+				continue
+			}
+			result, err := searchGraph(context.Background(), NewMemorySource(), expand, src, dest, searchRules{})
+			if errors.Is(err, ErrNoPath) {
+				if node, ok := reachedByBoth(t, expand, src, dest); ok {
+					t.Fatalf("seed %d, %d to %d: no path, but both reach %d", seed, src, dest, node)
+				}
+				continue
+			}
+			if err != nil { t.Fatal(err) }
+
+			// no node both sides reached joins them in fewer hops
+			nearest := -1
+			for node, d := range result.src.depth {
+				if other, ok := result.dest.depth[node]; ok && (nearest < 0 || d + other < nearest) {
+					nearest = d + other
+				}
+			}
+			for _, path := range result.paths(0) {
+				if len(path) - 1 != nearest {
+					t.Fatalf("seed %d, %d to %d: got %v, wanted %d hops", seed, src, dest, path, nearest)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package tmdbapi
+
+import (
+	"context"
+)
+
+// Source is the movie/actor graph the path search runs over. Client is the
+// TMDB backed implementation and MemorySource an in-memory one.
+type Source interface {
+	GetMovieFromTitleContext(ctx context.Context, movieTitle string) (MovieResource, error)
+	GetMovieFromIdContext(ctx context.Context, movieId int) (MovieResource, error)
+	GetMovieFromImdbIdContext(ctx context.Context, imdbId string) (MovieResource, error)
+	GetActorFromNameContext(ctx context.Context, actorName string) (ActorResource, error)
+	GetActorFromIdContext(ctx context.Context, actorId int) (ActorResource, error)
+	GetActorsContext(ctx context.Context, movieId int) (map[int]struct{}, error)
+	GetMoviesContext(ctx context.Context, actorId int) (map[int]struct{}, error)
+	GetNeighborsContext(ctx context.Context, movieId int) (map[int]struct{}, error)
+	GetCastContext(ctx context.Context, movieId int) (map[int]Role, error)
+	GetFilmographyContext(ctx context.Context, actorId int) (map[int]Role, error)
+}
+
+const defaultMaxRoutines = 20
+
+func maxRoutines(s Source) int {
+	if r, ok := s.(interface{ MaxRoutines() int }); ok && r.MaxRoutines() > 0 {
+		return r.MaxRoutines()
+	}
+	return defaultMaxRoutines
+}
@@ -0,0 +1,101 @@
+package tmdbapi
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A candidate is only picked over another exact title match when it is at
+// least this many times as popular.
+const ambiguousPopularityRatio = 2.0
+
+var titleYear = regexp.MustCompile(`^(.*\S)\s*\((\d{4})\)$`)
+
+// AmbiguousTitleError is returned when a title query matches several movies
+// exactly and none of them stands out. Candidates are ranked best first.
+type AmbiguousTitleError struct {
+	Query      string
+	Candidates []MovieResource
+}
+
+func (e *AmbiguousTitleError) Error() string {
+	return fmt.Sprintf("\"%s\" matches %d movies, add the year to choose one",
+		e.Query, len(e.Candidates),
+	)
+}
+
+// ParseTitle splits a year in parentheses off the end of a title query, so
+// "Dune (2021)" is "Dune" and 2021. year is 0 when there isn't one.
+func ParseTitle(query string) (title string, year int) {
+	query = strings.TrimSpace(query)
+	match := titleYear.FindStringSubmatch(query)
+	if match == nil {
+		return query, 0
+	}
+	year, _ = strconv.Atoi(match[2])
+	return match[1], year
+}
+
+// Year is the year of the movie's release date, or 0 when it isn't known.
+func (m MovieResource) Year() int {
+	if len(m.ReleaseDate) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(m.ReleaseDate[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// rankMovies drops candidates from other years when year isn't 0, then orders
+// exact title matches first and each group by popularity.
+func rankMovies(title string, year int, candidates []MovieResource) []MovieResource {
+	ranked := make([]MovieResource, 0, len(candidates))
+	for _, movie := range candidates {
+		if year == 0 || movie.Year() == 0 || movie.Year() == year {
+			ranked = append(ranked, movie)
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b MovieResource) int {
+		aExact, bExact := strings.EqualFold(a.Title, title), strings.EqualFold(b.Title, title)
+		switch {
+		case aExact && !bExact:
+			return -1
+		case bExact && !aExact:
+			return 1
+		case a.Popularity > b.Popularity:
+			return -1
+		case a.Popularity < b.Popularity:
+			return 1
+		}
+		return 0
+	})
+	return ranked
+}
+
+// pickMovie returns the best candidate for a title query, NoTitle when there
+// are none, or an AmbiguousTitleError when several match the title exactly
+// with similar popularity.
+func pickMovie(query string, candidates []MovieResource) (MovieResource, error) {
+	title, year := ParseTitle(query)
+	ranked := rankMovies(title, year, candidates)
+	if len(ranked) == 0 {
+		return NoTitle, nil
+	}
+
+	exact := 0
+	for exact < len(ranked) && strings.EqualFold(ranked[exact].Title, title) {
+		exact++
+	}
+	if exact > 1 && ranked[1].Popularity * ambiguousPopularityRatio >= ranked[0].Popularity {
+		return MovieResource{}, &AmbiguousTitleError{
+			Query:      query,
+			Candidates: ranked[:exact],
+		}
+	}
+	return ranked[0], nil
+}
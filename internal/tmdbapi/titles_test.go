@@ -0,0 +1,86 @@
+package tmdbapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct{
+		query string
+		title string
+		year  int
+	}{
+		{"Dune (2021)", "Dune", 2021},
+		{"  Dune(1984) ", "Dune", 1984},
+		{"Dune", "Dune", 0},
+		{"1917", "1917", 0},
+		{"(500) Days of Summer", "(500) Days of Summer", 0},
+		{"Blade Runner 2049 (2017)", "Blade Runner 2049", 2017},
+	}
+	for _, test := range tests {
+		title, year := ParseTitle(test.query)
+		if title != test.title || year != test.year {
+			t.Errorf("%q: got %q %d, wanted %q %d", test.query, title, year, test.title, test.year)
+		}
+	}
+}
+
+func TestPickMovie(t *testing.T) {
+	dune84 := MovieResource{Title: "Dune", Id: 841, ReleaseDate: "1984-12-14", Popularity: 30}
+	dune21 := MovieResource{Title: "Dune", Id: 438631, ReleaseDate: "2021-09-15", Popularity: 200}
+	dune00 := MovieResource{Title: "Dune", Id: 876, ReleaseDate: "2000-12-03", Popularity: 110}
+	part2 := MovieResource{Title: "Dune: Part Two", Id: 693134, ReleaseDate: "2024-02-27", Popularity: 900}
+
+	// part two is more popular but isn't an exact match
+	got, err := pickMovie("dune", []MovieResource{part2, dune84, dune21})
+	if err != nil || got != dune21 {
+		t.Errorf("got %v, %v, wanted the clearly more popular exact match", got, err)
+	}
+
+	got, err = pickMovie("Dune (1984)", []MovieResource{part2, dune84, dune21})
+	if err != nil || got != dune84 {
+		t.Errorf("got %v, %v, wanted the 1984 film", got, err)
+	}
+
+	_, err = pickMovie("Dune", []MovieResource{dune84, dune00, part2, dune21})
+	var ambiguous *AmbiguousTitleError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("got %v, wanted an ambiguous title", err)
+	}
+	if len(ambiguous.Candidates) != 3 || ambiguous.Candidates[0] != dune21 || ambiguous.Candidates[2] != dune84 {
+		t.Errorf("got candidates %v", ambiguous.Candidates)
+	}
+
+	if got, err := pickMovie("Dune", nil); err != nil || got != NoTitle {
+		t.Errorf("got %v, %v for no candidates", got, err)
+	}
+}
+
+func TestTitleYears(t *testing.T) {
+	sources := map[string]Source{
+		"memory": newTestSource(t),
+		"client": newTestClient(t),
+	}
+	for name, source := range sources {
+		_, err := GetPath(source, "Little Women", "Gravity")
+		var ambiguous *AmbiguousTitleError
+		if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+			t.Errorf("%s: got %v, wanted both Little Women", name, err)
+		}
+
+		path, err := GetPath(source, "Little Women (2019)", "Gravity")
+		if err != nil || path.From.Id != 331482 || path.Degree != 2 {
+			t.Errorf("%s: got %+v, %v", name, path, err)
+		}
+
+		path, err = GetPath(source, "Little Women (1994)", "Empire of the Sun")
+		if err != nil || path.From.Id != 9587 || path.Degree != 1 {
+			t.Errorf("%s: got %+v, %v", name, path, err)
+		}
+
+		if _, err := GetPath(source, "Gravity (1999)", "Fight Club"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: got %v for the wrong year", name, err)
+		}
+	}
+}
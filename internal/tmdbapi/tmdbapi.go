@@ -1,12 +1,16 @@
 package tmdbapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/BigStinko/mtmsolver/internal/tmdbcache"
@@ -14,21 +18,47 @@ import (
 
 type Client struct {
 	httpClient http.Client
-	cache      tmdbcache.Cache
+	cache      *tmdbcache.Cache
 	authHeader string
+	baseURL    string
 	searchFactor int
 	maxRoutines int
+	flights    *flightGroup
+	stats      *clientStats
+	limiter    *rateLimiter
+	retries    int
+	retryBase  time.Duration
+	retryMax   time.Duration
+	language   string
+	region     string
 }
 
+type clientStats struct {
+	apiCalls  atomic.Int64
+	coalesced atomic.Int64
+}
+
+// Stats counts the requests a Client has sent to TMDB and the credits fetches
+// that were served by sharing another caller's in-flight request.
+type Stats struct {
+	APICalls  int64
+	Coalesced int64
+}
+
+type Role = tmdbcache.Role
+
 type resource interface {
 	ActorResource | ActorQueryResult |
 	MovieResource | MovieQueryResult |
-	Credits 
+	Credits | FindResult
 }
 
 const (
-	baseURL = "https://api.themoviedb.org/3/"
-	defaultSearchParams = "?include_adult=false&page=1&query="
+	defaultBaseURL = "https://api.themoviedb.org/3/"
+	defaultLanguage = "en-US"
+	defaultRetries = 3
+	defaultRetryBase = 500 * time.Millisecond
+	defaultRetryMax = 10 * time.Second
 )
 
 var (
@@ -37,43 +67,155 @@ var (
 )
 
 func New(header string, timeout time.Duration) Client {
-	return Client{
+	c := Client{
 		httpClient: http.Client{
 			Timeout: timeout,
 		},
 		cache: tmdbcache.New(),
 		authHeader: header,
+		baseURL: defaultBaseURL,
 		searchFactor: 40,
-		maxRoutines: 20,
+		maxRoutines: defaultMaxRoutines,
+		flights: newFlightGroup(),
+		stats: &clientStats{},
+		retries: defaultRetries,
+		retryBase: defaultRetryBase,
+		retryMax: defaultRetryMax,
+		language: defaultLanguage,
+	}
+	c.cache.SetCastLimit(c.searchFactor)
+	return c
+}
+
+func (c *Client) SetBaseURL(url string) {
+	if !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+	c.baseURL = url
+}
+
+// SetRateLimit caps the client at requestsPerSecond, allowing bursts of up to
+// one second's worth of requests. Zero or less removes the limit.
+func (c *Client) SetRateLimit(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		c.limiter = nil
+		return
 	}
+	c.limiter = newRateLimiter(requestsPerSecond, int(requestsPerSecond))
 }
 
+// SetRetries sets how many times a request that failed with a 429 or 5xx is
+// retried, and the backoff between attempts. max also caps the wait a
+// Retry-After header asks for.
+func (c *Client) SetRetries(retries int, base, max time.Duration) {
+	c.retries = retries
+	c.retryBase = base
+	c.retryMax = max
+}
+
+// SetLanguage sets the ISO 639-1 language, optionally with a region as in
+// "fr-FR", that TMDB localizes titles and names in. Empty uses TMDB's default.
+// Credits already in the cache keep the language they were fetched in.
+func (c *Client) SetLanguage(language string) {
+	c.language = language
+}
+
+// SetRegion sets the ISO 3166-1 country code searches and release dates are
+// resolved for. Empty leaves it to TMDB.
+func (c *Client) SetRegion(region string) {
+	c.region = region
+}
+
+// SetCache makes the client use cache, which only serves it casts cut to the
+// client's searchfactor.
+func (c *Client) SetCache(cache *tmdbcache.Cache) {
+	c.cache = cache
+	c.cache.SetCastLimit(c.searchFactor)
+}
+
+func (c *Client) Cache() *tmdbcache.Cache {
+	return c.cache
+}
+
+// SetSearchFactor caps the cast members fetched per movie, billing order
+// first. Zero fetches the whole cast.
 func (c *Client) SetSearchFactor(s int) {
 	c.searchFactor = s
+	c.cache.SetCastLimit(s)
 }
 
 func (c *Client) SetMaxRoutines(r int) {
 	c.maxRoutines = r
 }
 
+func (c *Client) MaxRoutines() int {
+	return c.maxRoutines
+}
+
+func (c *Client) Stats() Stats {
+	return Stats{
+		APICalls:  c.stats.apiCalls.Load(),
+		Coalesced: c.stats.coalesced.Load(),
+	}
+}
+
 func (c *Client) GetMovies(actorId int) (map[int]struct{}, error) {
+	return c.GetMoviesContext(context.Background(), actorId)
+}
+
+func (c *Client) GetMoviesContext(
+	ctx context.Context,
+	actorId int,
+) (map[int]struct{}, error) {
 	if movies, ok := c.cache.GetMovies(actorId); ok {
 		return movies, nil
 	}
+	films, err := c.shareFilmography(ctx, actorId)
+	if err != nil { return nil, err }
+	return roleIds(films), nil
+}
 
-	url := baseURL
-	//url += "discover/movie?include_adult=false&include_video=false&language=en-US&page=1&sort_by=popularity.desc&with_people="
-	url += "person/"
-	url += strconv.Itoa(actorId)
-	url += "/movie_credits?language=en-US"
-	res, err := getResource[Credits](url, c)
+func (c *Client) GetFilmographyContext(
+	ctx context.Context,
+	actorId int,
+) (map[int]Role, error) {
+	if films, ok := c.cache.GetFilmography(actorId); ok {
+		return films, nil
+	}
+	return c.shareFilmography(ctx, actorId)
+}
+
+// shareFilmography fetches the filmography of an actor the caller has already
+// looked up in the cache and missed.
+func (c *Client) shareFilmography(
+	ctx context.Context,
+	actorId int,
+) (map[int]Role, error) {
+	key := "person/" + strconv.Itoa(actorId) + "/movie_credits"
+	return c.shareFetch(ctx, key, func(ctx context.Context) (map[int]Role, error) {
+		return c.fetchFilmography(ctx, actorId)
+	})
+}
+
+func (c *Client) fetchFilmography(
+	ctx context.Context,
+	actorId int,
+) (map[int]Role, error) {
+	// a fetch that finished since the caller's miss has filled the cache, and
+	// that miss was already counted
+	if films, ok := c.cache.PeekFilmography(actorId); ok {
+		return films, nil
+	}
+
+	url := c.endpoint("person/" + strconv.Itoa(actorId) + "/movie_credits", nil)
+	res, err := getResource[Credits](ctx, url, c)
 	if err != nil { return nil, err }
 
-	movies := make(map[int]struct{})
+	films := make(map[int]Role)
 
 	for _, movieRes := range res.Cast {
 		if movieRes.Character != "" {
-			movies[movieRes.Id] = struct{}{}
+			films[movieRes.Id] = Role{Name: movieRes.Title, Character: movieRes.Character}
 		}
 	}
 	/*for i := 0; i < min(len(res.Results), c.searchFactor); i++ {
@@ -81,54 +223,123 @@ func (c *Client) GetMovies(actorId int) (map[int]struct{}, error) {
 		movies[movie.Id] = struct{}{}
 	}*/
 
-	c.cache.AddMovies(actorId, movies)
-	return movies, nil
+	c.cache.AddFilmography(actorId, films)
+	return films, nil
 }
 
 func (c *Client) GetActors(movieId int) (map[int]struct{}, error) {
+	return c.GetActorsContext(context.Background(), movieId)
+}
+
+func (c *Client) GetActorsContext(
+	ctx context.Context,
+	movieId int,
+) (map[int]struct{}, error) {
 	if actors, ok := c.cache.GetActors(movieId); ok {
 		return actors, nil
 	}
+	cast, err := c.shareCast(ctx, movieId)
+	if err != nil { return nil, err }
+	return roleIds(cast), nil
+}
+
+func (c *Client) GetCastContext(
+	ctx context.Context,
+	movieId int,
+) (map[int]Role, error) {
+	if cast, ok := c.cache.GetCast(movieId); ok {
+		return cast, nil
+	}
+	return c.shareCast(ctx, movieId)
+}
 
-	url := baseURL + "movie/" + strconv.Itoa(movieId) + "/credits"
-	res, err := getResource[Credits](url, c)
+// shareCast fetches the cast of a movie the caller has already looked up in
+// the cache and missed.
+func (c *Client) shareCast(
+	ctx context.Context,
+	movieId int,
+) (map[int]Role, error) {
+	key := "movie/" + strconv.Itoa(movieId) + "/credits"
+	return c.shareFetch(ctx, key, func(ctx context.Context) (map[int]Role, error) {
+		return c.fetchCast(ctx, movieId)
+	})
+}
+
+func (c *Client) fetchCast(
+	ctx context.Context,
+	movieId int,
+) (map[int]Role, error) {
+	if cast, ok := c.cache.PeekCast(movieId); ok {
+		return cast, nil
+	}
+
+	url := c.endpoint("movie/" + strconv.Itoa(movieId) + "/credits", nil)
+	res, err := getResource[Credits](ctx, url, c)
 	if err != nil { return nil, err }
 
-	actors := make(map[int]struct{})
+	cast := make(map[int]Role)
 	limit := c.searchFactor
 	for _, actorRes := range res.Cast {
-		if limit == 0 {
+		if c.searchFactor > 0 && limit == 0 {
 			break
 		}
 		if actorRes.Character != "" {
-			actors[actorRes.Id] = struct{}{}
+			cast[actorRes.Id] = Role{Name: actorRes.Name, Character: actorRes.Character}
 			limit--
 		}
 	}
 
-	c.cache.AddActors(movieId, actors)
-	return actors, nil
+	c.cache.AddCast(movieId, cast)
+	return cast, nil
 }
 
 func (c *Client) GetMovieFromTitle(movieTitle string) (MovieResource, error) {
-	query := fixStringForURL(movieTitle)
-	url := baseURL + "search/movie" + defaultSearchParams + query
-	
-	res, err := getResource[MovieQueryResult](url, c)
+	return c.GetMovieFromTitleContext(context.Background(), movieTitle)
+}
+
+func (c *Client) GetMovieFromTitleContext(
+	ctx context.Context,
+	movieTitle string,
+) (MovieResource, error) {
+	candidates, err := c.SearchMoviesContext(ctx, movieTitle)
 	if err != nil { return MovieResource{}, err }
+	return pickMovie(movieTitle, candidates)
+}
 
-	if res.TotalResults > 0 {
-		return res.Results[0], nil
+func (c *Client) SearchMovies(query string) ([]MovieResource, error) {
+	return c.SearchMoviesContext(context.Background(), query)
+}
+
+// SearchMoviesContext returns the first page of TMDB's matches for query, best
+// match first. A year in parentheses at the end of query, as in
+// "Dune (2021)", limits the results to that year.
+func (c *Client) SearchMoviesContext(
+	ctx context.Context,
+	query string,
+) ([]MovieResource, error) {
+	title, year := ParseTitle(query)
+	params := searchParams(title)
+	if year != 0 {
+		params.Set("primary_release_year", strconv.Itoa(year))
 	}
-	fmt.Println(url)
-	return NoTitle, nil
+	url := c.endpoint("search/movie", params)
+
+	res, err := getResource[MovieQueryResult](ctx, url, c)
+	if err != nil { return nil, err }
+	return rankMovies(title, year, res.Results), nil
 }
 
 func (c *Client) GetActorFromName(actorName string) (ActorResource, error) {
-	query := fixStringForURL(actorName)
-	url := baseURL + "search/person" + defaultSearchParams + query
+	return c.GetActorFromNameContext(context.Background(), actorName)
+}
+
+func (c *Client) GetActorFromNameContext(
+	ctx context.Context,
+	actorName string,
+) (ActorResource, error) {
+	url := c.endpoint("search/person", searchParams(actorName))
 
-	res, err := getResource[ActorQueryResult](url, c)
+	res, err := getResource[ActorQueryResult](ctx, url, c)
 	if err != nil { return ActorResource{}, err }
 
 	if len(res.Results) > 0 {
@@ -138,21 +349,60 @@ func (c *Client) GetActorFromName(actorName string) (ActorResource, error) {
 }
 
 func (c *Client) GetActorFromId(actorId int) (ActorResource, error) {
-	url := baseURL + "person/" + strconv.Itoa(actorId)
-	return getResource[ActorResource](url, c)
+	return c.GetActorFromIdContext(context.Background(), actorId)
+}
+
+func (c *Client) GetActorFromIdContext(
+	ctx context.Context,
+	actorId int,
+) (ActorResource, error) {
+	url := c.endpoint("person/" + strconv.Itoa(actorId), nil)
+	return getResource[ActorResource](ctx, url, c)
 }
 
 func (c *Client) GetMovieFromId(movieId int) (MovieResource, error) {
-	url := baseURL + "movie/" + strconv.Itoa(movieId)
-	return getResource[MovieResource](url, c)
+	return c.GetMovieFromIdContext(context.Background(), movieId)
+}
+
+func (c *Client) GetMovieFromIdContext(
+	ctx context.Context,
+	movieId int,
+) (MovieResource, error) {
+	url := c.endpoint("movie/" + strconv.Itoa(movieId), nil)
+	return getResource[MovieResource](ctx, url, c)
+}
+
+func (c *Client) GetMovieFromImdbId(imdbId string) (MovieResource, error) {
+	return c.GetMovieFromImdbIdContext(context.Background(), imdbId)
+}
+
+// GetMovieFromImdbIdContext resolves an IMDb id like tt0133093 through TMDB's
+// find endpoint, returning NoTitle when TMDB doesn't know it.
+func (c *Client) GetMovieFromImdbIdContext(
+	ctx context.Context,
+	imdbId string,
+) (MovieResource, error) {
+	if !validImdbId(imdbId) {
+		return MovieResource{}, fmt.Errorf("invalid IMDb id %q", imdbId)
+	}
+	url := c.endpoint("find/" + imdbId, url.Values{"external_source": {"imdb_id"}})
+
+	res, err := getResource[FindResult](ctx, url, c)
+	if err != nil { return MovieResource{}, err }
+
+	if len(res.MovieResults) > 0 {
+		return res.MovieResults[0], nil
+	}
+	return NoTitle, nil
 }
 
 func (c *Client) OverlappingActors(leftId, rightId int) ([]int, error) {
-	url := baseURL + "movie/" + strconv.Itoa(leftId) + "/credits"
-	creditResLeft, err := getResource[Credits](url, c)	
+	ctx := context.Background()
+	url := c.endpoint("movie/" + strconv.Itoa(leftId) + "/credits", nil)
+	creditResLeft, err := getResource[Credits](ctx, url, c)	
 	if err != nil { return nil, err }
-	url = baseURL + "movie/" + strconv.Itoa(rightId) + "/credits"
-	creditResRight, err := getResource[Credits](url, c)
+	url = c.endpoint("movie/" + strconv.Itoa(rightId) + "/credits", nil)
+	creditResRight, err := getResource[Credits](ctx, url, c)
 	if err != nil { return nil, err }
 	
 	actorsLeft := make(map[int]struct{})
@@ -182,11 +432,35 @@ func (c *Client) OverlappingActors(leftId, rightId int) ([]int, error) {
 	return out, nil
 }
 
+// shareFetch runs fetch unless the same key is already being fetched, in which
+// case it waits for that result instead. If the shared fetch was cancelled by
+// the other caller's context while ours is still live, it fetches again.
+func (c *Client) shareFetch(
+	ctx context.Context,
+	key string,
+	fetch func(context.Context) (map[int]Role, error),
+) (map[int]Role, error) {
+	for {
+		val, shared, err := c.flights.do(ctx, key, func() (any, error) {
+			return fetch(ctx)
+		})
+		if shared {
+			c.stats.coalesced.Add(1)
+		}
+		if shared && isContextErr(err) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil { return nil, err }
+		return val.(map[int]Role), nil
+	}
+}
+
 func (c *Client) newRequest(
+	ctx context.Context,
 	method, url string,
 	body io.Reader,
 ) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil { return nil, err }
 
 	req.Header.Add("accept", "application/json")
@@ -194,26 +468,95 @@ func (c *Client) newRequest(
 	return req, nil
 }
 
-func getResource[R resource](url string, c *Client) (R, error) {
+func getResource[R resource](
+	ctx context.Context,
+	url string,
+	c *Client,
+) (R, error) {
 	var zero R
-	
-	request, err := c.newRequest("GET", url, nil)
-	if err != nil { return zero, err }
 
+	for attempt := 0; ; attempt++ {
+		dat, err := c.fetch(ctx, url)
+		if err == nil {
+			var res R
+			err = json.Unmarshal(dat, &res)
+			if err != nil { return zero, err }
+			return res, nil
+		}
+
+		apiErr, ok := err.(*APIError)
+		if !ok || !apiErr.Retryable() || attempt >= c.retries {
+			return zero, err
+		}
+		delay := min(apiErr.RetryAfter, c.retryMax)
+		if delay == 0 {
+			delay = backoff(c.retryBase, c.retryMax, attempt)
+		}
+		if err := sleep(ctx, delay); err != nil { return zero, err }
+	}
+}
+
+func (c *Client) fetch(ctx context.Context, url string) ([]byte, error) {
+	request, err := c.newRequest(ctx, "GET", url, nil)
+	if err != nil { return nil, err }
+
+	if err := c.limiter.wait(ctx); err != nil { return nil, err }
+	c.stats.apiCalls.Add(1)
 	response, err := c.httpClient.Do(request)
-	if err != nil { return zero, err }
+	if err != nil { return nil, err }
 	defer response.Body.Close()
 
 	dat, err := io.ReadAll(response.Body)
-	if err != nil { return zero, err }
+	if err != nil { return nil, err }
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, newAPIError(response, url, dat)
+	}
+	return dat, nil
+}
 
-	var res R
-	err = json.Unmarshal(dat, &res)
-	if err != nil { return zero, err }
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func roleIds(roles map[int]Role) map[int]struct{} {
+	ids := make(map[int]struct{}, len(roles))
+	for id := range roles {
+		ids[id] = struct{}{}
+	}
+	return ids
+}
 
-	return res, nil
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
 
-func fixStringForURL(str string) string {
-	return strings.ToLower(strings.ReplaceAll(str, " ", "+"))
+// endpoint returns the URL of a TMDB API path with params, plus the client's
+// language and region, encoded as its query string.
+func (c *Client) endpoint(path string, params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	if c.language != "" {
+		params.Set("language", c.language)
+	}
+	if c.region != "" {
+		params.Set("region", c.region)
+	}
+	return c.baseURL + path + "?" + params.Encode()
+}
+
+func searchParams(query string) url.Values {
+	return url.Values{
+		"include_adult": {"false"},
+		"page":          {"1"},
+		"query":         {query},
+	}
 }
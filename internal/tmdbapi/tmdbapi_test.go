@@ -0,0 +1,238 @@
+package tmdbapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BigStinko/mtmsolver/internal/tmdbtest"
+)
+
+func TestQueriesAreEncoded(t *testing.T) {
+	server := tmdbtest.NewServer()
+	defer server.Close()
+	client := New("Bearer test-token", time.Second * 5)
+	client.SetBaseURL(server.BaseURL())
+	client.SetRegion("FR")
+
+	titles := []string{"Fast & Furious", "#Alive", "What If?", "Amélie", "千と千尋の神隠し", "50% + 1 (2020)"}
+	for _, title := range titles {
+		if _, err := client.SearchMovies(title); err != nil { t.Fatal(err) }
+	}
+	if _, err := client.GetActorFromName("Zoë Kravitz"); err != nil { t.Fatal(err) }
+
+	queries := server.Queries("/search/movie")
+	if len(queries) != len(titles) {
+		t.Fatalf("server got %d searches, wanted %d", len(queries), len(titles))
+	}
+	for i, want := range []string{"Fast & Furious", "#Alive", "What If?", "Amélie", "千と千尋の神隠し", "50% + 1"} {
+		if got := queries[i].Get("query"); got != want {
+			t.Errorf("searched for %q, wanted %q", got, want)
+		}
+		if got := queries[i].Get("language"); got != "en-US" {
+			t.Errorf("search %q sent language %q, wanted en-US", want, got)
+		}
+		if got := queries[i].Get("region"); got != "FR" {
+			t.Errorf("search %q sent region %q, wanted FR", want, got)
+		}
+	}
+	if got := queries[5].Get("primary_release_year"); got != "2020" {
+		t.Errorf("sent primary_release_year %q, wanted 2020", got)
+	}
+	people := server.Queries("/search/person")
+	if len(people) != 1 || people[0].Get("query") != "Zoë Kravitz" {
+		t.Errorf("person searches were %v", people)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	server := tmdbtest.NewServer()
+	defer server.Close()
+	client := New("Bearer test-token", time.Second * 5)
+	client.SetBaseURL(server.BaseURL())
+	client.SetLanguage("fr-FR")
+
+	movie, err := client.GetMovieFromTitle("La Cité des enfants perdus")
+	if err != nil { t.Fatal(err) }
+	if movie.Id != 902 || movie.Title != "La Cité des enfants perdus" {
+		t.Fatalf("got movie %v, wanted the French title of 902", movie)
+	}
+
+	path, err := GetPath(&client, "tmdb:902", "tmdb:10110")
+	if err != nil { t.Fatal(err) }
+	want := []string{"La Cité des enfants perdus", "Alien, la résurrection", "L'Empire du soleil"}
+	if len(path.Hops) != len(want) - 1 {
+		t.Fatalf("got path %v, wanted %v", path, want)
+	}
+	titles := []string{path.From.Title}
+	for _, hop := range path.Hops {
+		titles = append(titles, hop.To.Title)
+	}
+	if !slices.Equal(titles, want) {
+		t.Errorf("got titles %q, wanted %q", titles, want)
+	}
+
+	for _, query := range server.Queries("/") {
+		if query.Get("language") != "fr-FR" {
+			t.Errorf("request sent language %q, wanted fr-FR", query.Get("language"))
+		}
+	}
+}
+
+func newRetryingClient(t *testing.T) (*Client, *tmdbtest.Server) {
+	t.Helper()
+	server := tmdbtest.NewServer()
+	t.Cleanup(server.Close)
+
+	client := New("Bearer test-token", time.Second * 5)
+	client.SetBaseURL(server.BaseURL())
+	client.SetRetries(3, time.Millisecond, 5 * time.Millisecond)
+	return &client, server
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	client, server := newRetryingClient(t)
+	server.FailNext(2, http.StatusServiceUnavailable, "")
+
+	actors, err := client.GetActors(550)
+	if err != nil { t.Fatal(err) }
+	if len(actors) != 3 {
+		t.Errorf("got actors %v", actors)
+	}
+	if requests := server.Requests(); requests != 3 {
+		t.Errorf("server got %d requests, wanted 3", requests)
+	}
+}
+
+func TestRetryAfterIsHonored(t *testing.T) {
+	client, server := newRetryingClient(t)
+	client.SetRetries(3, time.Millisecond, 5 * time.Second)
+	server.FailNext(1, http.StatusTooManyRequests, "1")
+
+	start := time.Now()
+	if _, err := client.GetActors(550); err != nil { t.Fatal(err) }
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("retried after %s, wanted at least the 1s Retry-After", waited)
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	client, server := newRetryingClient(t)
+	server.FailNext(1, http.StatusTooManyRequests, "3600")
+
+	start := time.Now()
+	if _, err := client.GetActors(550); err != nil { t.Fatal(err) }
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("waited %s, wanted the 5ms backoff maximum", waited)
+	}
+
+	client.SetRetries(3, time.Millisecond, time.Hour)
+	server.FailNext(1, http.StatusTooManyRequests, "3600")
+	ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+	defer cancel()
+	_, err := client.GetActorsContext(ctx, 680)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted the context's deadline", err)
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	client, server := newRetryingClient(t)
+	server.FailNext(10, http.StatusTooManyRequests, "")
+
+	_, err := client.GetActors(550)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got error %v, wanted rate limited", err)
+	}
+	if requests := server.Requests(); requests != 4 {
+		t.Errorf("server got %d requests, wanted 4", requests)
+	}
+}
+
+func TestStatusErrorsAreTyped(t *testing.T) {
+	client, server := newRetryingClient(t)
+
+	_, err := client.GetMovieFromId(1)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, wanted not found", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got error %v, wanted a 404 APIError", err)
+	}
+
+	unauthorized := New("", time.Second * 5)
+	unauthorized.SetBaseURL(server.BaseURL())
+	_, err = unauthorized.GetActors(550)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("got error %v, wanted unauthorized", err)
+	}
+	if requests := server.Requests(); requests != 2 {
+		t.Errorf("server got %d requests, client errors should not be retried", requests)
+	}
+}
+
+func TestGetPathReportsAPIErrors(t *testing.T) {
+	client, server := newRetryingClient(t)
+	server.SetToken("test-token")
+
+	rejected := New("Bearer expired-token", time.Second * 5)
+	rejected.SetBaseURL(server.BaseURL())
+	_, err := GetPath(&rejected, "Midsommar", "Gravity")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("bad token: got error %v, wanted unauthorized", err)
+	}
+	if apiErr.TMDBCode != 7 || !strings.Contains(apiErr.TMDBMessage, "Invalid API key") {
+		t.Errorf("got TMDB code %d and message %q", apiErr.TMDBCode, apiErr.TMDBMessage)
+	}
+	if apiErr.Retryable() {
+		t.Errorf("401 reported as retryable")
+	}
+
+	_, err = GetPath(client, "tmdb:1", "Gravity")
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) || errors.Is(err, ErrUnauthorized) {
+		t.Errorf("unknown id: got error %v, wanted not found", err)
+	}
+
+	server.FailNext(10, http.StatusBadGateway, "")
+	_, err = GetPath(client, "Midsommar", "Gravity")
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrServer) || !apiErr.Retryable() {
+		t.Errorf("server error: got error %v, wanted a retryable server error", err)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := map[string]string{
+		"https://api.themoviedb.org/3/movie/550?language=en-US":
+			"https://api.themoviedb.org/3/movie/550?language=en-US",
+		"https://api.themoviedb.org/3/movie/550?api_key=secret&language=en-US":
+			"https://api.themoviedb.org/3/movie/550?language=en-US",
+		"http://proxy/3/search/movie?query=a&access_token=secret":
+			"http://proxy/3/search/movie?query=a",
+	}
+	for in, want := range tests {
+		if got := redactURL(in); got != want {
+			t.Errorf("redactURL(%q) = %q, wanted %q", in, got, want)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	client, _ := newRetryingClient(t)
+	client.SetRateLimit(20)
+
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		if _, err := client.GetMovieFromId(550); err != nil { t.Fatal(err) }
+	}
+	// 20 requests come out of the initial burst, the other 10 at 20 per second
+	if elapsed := time.Since(start); elapsed < 450 * time.Millisecond {
+		t.Errorf("30 requests at 20/s took %s", elapsed)
+	}
+}
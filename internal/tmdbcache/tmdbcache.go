@@ -1,80 +1,525 @@
 package tmdbcache
 
 import (
+	"bufio"
+	"container/list"
+	"encoding/json"
 	_"fmt"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Cache struct {
 	actorsMovies sync.Map
 	moviesActors sync.Map
 	neighbors    sync.Map
+	ttl          time.Duration
+	castLimit    int
+	now          func() time.Time
+	logMu        sync.Mutex
+	log          *os.File
+	lruMu        sync.Mutex
+	lru          *list.List
+	limits       Limits
+	bytes        int
+	hits         atomic.Int64
+	misses       atomic.Int64
+	evictions    atomic.Int64
 }
 
-func New() Cache {
-	return Cache{
+// Limits bounds the memory a cache holds across all of its tables. Once either
+// budget is exceeded the least recently used entries are evicted. Zero means
+// no limit.
+type Limits struct {
+	MaxEntries int
+	MaxBytes   int
+}
+
+type Stats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Entries   int
+	Bytes     int
+}
+
+// Role is one credit: the name of the movie or person on the other side of it
+// and the character played.
+type Role struct {
+	Name      string `json:"name"`
+	Character string `json:"character"`
+}
+
+type entry struct {
+	ids     map[int]struct{}
+	roles   map[int]Role
+	fetched time.Time
+	// castLimit is the cast limit a cast, or the casts behind a neighbor
+	// set, were fetched under, and -1 when the log didn't record it
+	castLimit int
+	elem    *list.Element
+	size    int
+}
+
+type lruKey struct {
+	table table
+	id    int
+	entry *entry
+}
+
+type table string
+
+const (
+	actorsMoviesTable table = "actors_movies"
+	moviesActorsTable table = "movies_actors"
+	neighborsTable    table = "neighbors"
+)
+
+type logRecord struct {
+	Table     table `json:"table"`
+	Id        int   `json:"id"`
+	Ids       []int        `json:"ids"`
+	Roles     map[int]Role `json:"roles,omitempty"`
+	Fetched   int64        `json:"fetched"`
+	CastLimit *int         `json:"cast_limit,omitempty"`
+}
+
+func New() *Cache {
+	return &Cache{
 		actorsMovies: sync.Map{},
 		moviesActors: sync.Map{},
 		neighbors:    sync.Map{},
+		now:          time.Now,
+		lru:          list.New(),
+	}
+}
+
+func NewBounded(limits Limits) *Cache {
+	c := New()
+	c.limits = limits
+	return c
+}
+
+// Open returns a cache backed by an append-only log at path. Entries already
+// in the log are loaded, and every later Add is appended to it. A ttl of zero
+// keeps entries forever. Casts and neighbor sets are logged with the cast
+// limit they were fetched under, and only served under the same limit.
+func Open(path string, ttl time.Duration) (*Cache, error) {
+	c := New()
+	c.ttl = ttl
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil { return nil, err }
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec logRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// a partial record from an interrupted write
+			continue
+		}
+		e := &entry{
+			ids:       make(map[int]struct{}, len(rec.Ids)),
+			roles:     rec.Roles,
+			fetched:   time.Unix(rec.Fetched, 0),
+			castLimit: -1,
+		}
+		if rec.CastLimit != nil {
+			e.castLimit = *rec.CastLimit
+		}
+		for _, id := range rec.Ids {
+			e.ids[id] = struct{}{}
+		}
+		if c.table(rec.Table) != nil && !c.expired(e) {
+			c.store(rec.Table, rec.Id, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	c.log = file
+	return c, nil
+}
+
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.ttl = ttl
+}
+
+// SetCastLimit sets the cap on cast members that casts added from now on were
+// fetched with, 0 for whole casts. Casts and neighbor sets added under another
+// limit are misses from then on.
+func (c *Cache) SetCastLimit(limit int) {
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+	c.castLimit = limit
+}
+
+func (c *Cache) SetLimits(limits Limits) {
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+	c.limits = limits
+	c.evict()
+}
+
+func (c *Cache) Stats() Stats {
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   c.lru.Len(),
+		Bytes:     c.bytes,
 	}
 }
 
+func (c *Cache) Close() error {
+	c.logMu.Lock()
+	defer c.logMu.Unlock()
+	if c.log == nil {
+		return nil
+	}
+	err := c.log.Close()
+	c.log = nil
+	return err
+}
+
+// Compact rewrites the log so it only holds the live entries.
+func (c *Cache) Compact() error {
+	c.logMu.Lock()
+	defer c.logMu.Unlock()
+	if c.log == nil {
+		return nil
+	}
+
+	path := c.log.Name()
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmdbcache-*")
+	if err != nil { return err }
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+
+	for _, t := range []table{actorsMoviesTable, moviesActorsTable, neighborsTable} {
+		c.table(t).Range(func(key, val any) bool {
+			e := val.(*entry)
+			if c.expired(e) {
+				return true
+			}
+			err = encoder.Encode(newLogRecord(t, key.(int), e))
+			return err == nil
+		})
+		if err != nil { break }
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil { return err }
+	c.log.Close()
+	c.log = file
+	return nil
+}
+
 func (c *Cache) GetMovies(actorId int) (map[int]struct{}, bool) {
 	//fmt.Println("GetMovies")
-	val, ok := c.actorsMovies.Load(actorId)
-	if !ok {
-		return nil, ok
-	}
-	m, ok := val.(map[int]struct{})
-	return m, ok
+	return c.get(actorsMoviesTable, actorId)
 }
 
 func (c *Cache) AddMovies(actorId int, movies map[int]struct{}) {
 	//fmt.Println("AddMovies")
-	c.actorsMovies.Store(actorId, movies)
+	c.add(actorsMoviesTable, actorId, movies)
 }
 
 func (c *Cache) AddMovie(actorId, movieId int) {
 	//fmt.Println("AddMovie")
-	val, _ := c.actorsMovies.Load(actorId)
-	movies := val.(map[int]struct{}) 
+	movies, _ := c.GetMovies(actorId)
+	movies = copySet(movies)
 	movies[movieId] = struct{}{}
-	c.actorsMovies.Store(actorId, movies)
+	c.AddMovies(actorId, movies)
+}
+
+// GetFilmography returns the roles of actorId's movies keyed by movie id, if
+// they were added with AddFilmography.
+func (c *Cache) GetFilmography(actorId int) (map[int]Role, bool) {
+	return c.getRoles(actorsMoviesTable, actorId)
+}
+
+// PeekFilmography is GetFilmography without counting a hit or miss, for
+// checking again whether a fetch already counted has been done meanwhile.
+func (c *Cache) PeekFilmography(actorId int) (map[int]Role, bool) {
+	return rolesOf(c.lookup(actorsMoviesTable, actorId))
+}
+
+func (c *Cache) AddFilmography(actorId int, films map[int]Role) {
+	c.addRoles(actorsMoviesTable, actorId, films)
 }
 
 func (c *Cache) GetActors(movieId int) (map[int]struct{}, bool) {
 	//fmt.Println("GetActors")
-	val, ok := c.moviesActors.Load(movieId)
-	if !ok {
-		return nil, ok
-	}
-	a, ok := val.(map[int]struct{})
-	return a, ok
+	return c.get(moviesActorsTable, movieId)
 }
 
 func (c *Cache) AddActors(movieId int, actors map[int]struct{}) {
 	//fmt.Println("AddActors")
-	c.moviesActors.Store(movieId, actors)
+	c.add(moviesActorsTable, movieId, actors)
 }
 
 func (c *Cache) AddActor(movieId, actorId int) {
 	//fmt.Println("AddActors")
-	val, _ := c.moviesActors.Load(movieId)
-	actors := val.(map[int]struct{})
+	actors, _ := c.GetActors(movieId)
+	actors = copySet(actors)
 	actors[actorId] = struct{}{}
-	c.moviesActors.Store(movieId, actors)
+	c.AddActors(movieId, actors)
+}
+
+// GetCast returns the roles in movieId keyed by actor id, if they were added
+// with AddCast.
+func (c *Cache) GetCast(movieId int) (map[int]Role, bool) {
+	return c.getRoles(moviesActorsTable, movieId)
+}
+
+// PeekCast is GetCast without counting a hit or miss.
+func (c *Cache) PeekCast(movieId int) (map[int]Role, bool) {
+	return rolesOf(c.lookup(moviesActorsTable, movieId))
+}
+
+func (c *Cache) AddCast(movieId int, cast map[int]Role) {
+	c.addRoles(moviesActorsTable, movieId, cast)
+}
+
+// DropCasts removes every cast and the neighbor sets worked out from them, for
+// when casts will be fetched with a different limit. Filmographies are kept,
+// as is the log, where each cast keeps the limit it was fetched under.
+func (c *Cache) DropCasts() {
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+	for _, t := range []table{moviesActorsTable, neighborsTable} {
+		c.table(t).Range(func(key, val any) bool {
+			if c.table(t).CompareAndDelete(key, val) {
+				c.untrack(val.(*entry))
+			}
+			return true
+		})
+	}
 }
 
 func (c *Cache) GetNeighbors(movieId int) (map[int]struct{}, bool) {
 	//fmt.Println("GetNeighbors")
-	val, ok := c.neighbors.Load(movieId)
+	neighbors, ok := c.get(neighborsTable, movieId)
 	if !ok {
 		return make(map[int]struct{}), ok
 	}
-	return val.(map[int]struct{}), ok
+	return neighbors, ok
 }
 
 func (c *Cache) AddNeighbors(movieId int, neighbors map[int]struct{}) {
 	//fmt.Println("AddNeighbors")
-	c.neighbors.Store(movieId, neighbors)
+	c.add(neighborsTable, movieId, neighbors)
+}
+
+func (c *Cache) get(t table, id int) (map[int]struct{}, bool) {
+	e, ok := c.getEntry(t, id)
+	if !ok {
+		return nil, ok
+	}
+	return e.ids, true
+}
+
+// getRoles counts an entry added without roles as a miss, since the caller
+// has to fetch them.
+func (c *Cache) getRoles(t table, id int) (map[int]Role, bool) {
+	roles, ok := rolesOf(c.lookup(t, id))
+	c.count(ok)
+	return roles, ok
+}
+
+func rolesOf(e *entry, ok bool) (map[int]Role, bool) {
+	if !ok || e.roles == nil {
+		return nil, false
+	}
+	return e.roles, true
+}
+
+func (c *Cache) getEntry(t table, id int) (*entry, bool) {
+	e, ok := c.lookup(t, id)
+	c.count(ok)
+	return e, ok
+}
+
+func (c *Cache) count(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
 }
+
+// lookup finds a live entry, dropping it if it has expired or its cast was cut
+// to another limit, without touching the hit and miss counters.
+func (c *Cache) lookup(t table, id int) (*entry, bool) {
+	val, ok := c.table(t).Load(id)
+	if !ok {
+		return nil, ok
+	}
+	e := val.(*entry)
+
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+	if c.expired(e) || c.cutDifferently(t, e) {
+		if c.table(t).CompareAndDelete(id, val) {
+			c.untrack(e)
+		}
+		return nil, false
+	}
+	if e.elem != nil {
+		c.lru.MoveToFront(e.elem)
+	}
+	return e, true
+}
+
+func (c *Cache) add(t table, id int, ids map[int]struct{}) {
+	e := &entry{ids: ids, fetched: c.now(), castLimit: c.currentCastLimit()}
+	c.store(t, id, e)
+	c.append(newLogRecord(t, id, e))
+}
+
+func (c *Cache) addRoles(t table, id int, roles map[int]Role) {
+	ids := make(map[int]struct{}, len(roles))
+	for i := range roles {
+		ids[i] = struct{}{}
+	}
+	e := &entry{ids: ids, roles: roles, fetched: c.now(), castLimit: c.currentCastLimit()}
+	c.store(t, id, e)
+	c.append(newLogRecord(t, id, e))
+}
+
+func (c *Cache) store(t table, id int, e *entry) {
+	e.size = entrySize(e)
+
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+	if old, loaded := c.table(t).Swap(id, e); loaded {
+		c.untrack(old.(*entry))
+	}
+	e.elem = c.lru.PushFront(lruKey{table: t, id: id, entry: e})
+	c.bytes += e.size
+	c.evict()
+}
+
+// evict must be called with lruMu held.
+func (c *Cache) evict() {
+	for c.overBudget() {
+		back := c.lru.Back()
+		if back == nil {
+			return
+		}
+		key := back.Value.(lruKey)
+		c.table(key.table).CompareAndDelete(key.id, key.entry)
+		c.untrack(key.entry)
+		c.evictions.Add(1)
+	}
+}
+
+func (c *Cache) overBudget() bool {
+	return (c.limits.MaxEntries > 0 && c.lru.Len() > c.limits.MaxEntries) ||
+		(c.limits.MaxBytes > 0 && c.bytes > c.limits.MaxBytes)
+}
+
+// untrack must be called with lruMu held.
+func (c *Cache) untrack(e *entry) {
+	if e.elem == nil {
+		return
+	}
+	c.lru.Remove(e.elem)
+	e.elem = nil
+	c.bytes -= e.size
+}
+
+// entrySize is a rough estimate of the memory an entry holds.
+func entrySize(e *entry) int {
+	size := 64 + 16*len(e.ids)
+	for _, role := range e.roles {
+		size += 48 + len(role.Name) + len(role.Character)
+	}
+	return size
+}
+
+func (c *Cache) append(rec logRecord) {
+	c.logMu.Lock()
+	defer c.logMu.Unlock()
+	if c.log == nil {
+		return
+	}
+	dat, err := json.Marshal(rec)
+	if err != nil { return }
+	// a failed write only costs a refetch on the next run
+	c.log.Write(append(dat, '\n'))
+}
+
+func (c *Cache) table(t table) *sync.Map {
+	switch t {
+	case actorsMoviesTable:
+		return &c.actorsMovies
+	case moviesActorsTable:
+		return &c.moviesActors
+	case neighborsTable:
+		return &c.neighbors
+	}
+	return nil
+}
+
+func (c *Cache) expired(e *entry) bool {
+	return c.ttl > 0 && c.now().Sub(e.fetched) > c.ttl
+}
+
+func (c *Cache) currentCastLimit() int {
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+	return c.castLimit
+}
+
+// cutDifferently reports whether e holds casts cut to another limit than the
+// cache's. It must be called with lruMu held.
+func (c *Cache) cutDifferently(t table, e *entry) bool {
+	return t != actorsMoviesTable && e.castLimit != c.castLimit
+}
+
+func newLogRecord(t table, id int, e *entry) logRecord {
+	ids := make([]int, 0, len(e.ids))
+	for i := range e.ids {
+		ids = append(ids, i)
+	}
+	rec := logRecord{Table: t, Id: id, Ids: ids, Roles: e.roles, Fetched: e.fetched.Unix()}
+	if t != actorsMoviesTable && e.castLimit >= 0 {
+		limit := e.castLimit
+		rec.CastLimit = &limit
+	}
+	return rec
+}
+
+func copySet(set map[int]struct{}) map[int]struct{} {
+	out := make(map[int]struct{}, len(set)+1)
+	for k := range set {
+		out[k] = struct{}{}
+	}
+	return out
+}
+
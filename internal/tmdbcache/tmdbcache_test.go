@@ -0,0 +1,302 @@
+package tmdbcache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func set(ids ...int) map[int]struct{} {
+	out := make(map[int]struct{})
+	for _, id := range ids {
+		out[id] = struct{}{}
+	}
+	return out
+}
+
+func TestPersistentCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	c, err := Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	c.AddMovies(819, set(550, 10220))
+	c.AddActors(550, set(819, 287))
+	c.AddNeighbors(550, set(550, 10220))
+	c.AddMovie(819, 1)
+	if err := c.Close(); err != nil { t.Fatal(err) }
+
+	c, err = Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+
+	movies, ok := c.GetMovies(819)
+	if !ok || len(movies) != 3 {
+		t.Errorf("got movies %v, %v", movies, ok)
+	}
+	actors, ok := c.GetActors(550)
+	if !ok || len(actors) != 2 {
+		t.Errorf("got actors %v, %v", actors, ok)
+	}
+	neighbors, ok := c.GetNeighbors(550)
+	if !ok || len(neighbors) != 2 {
+		t.Errorf("got neighbors %v, %v", neighbors, ok)
+	}
+	if _, ok := c.GetActors(680); ok {
+		t.Errorf("got actors for a movie never added")
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+	now := time.Unix(1700000000, 0)
+
+	c, err := Open(path, time.Hour)
+	if err != nil { t.Fatal(err) }
+	c.now = func() time.Time { return now }
+	c.AddActors(550, set(819))
+
+	now = now.Add(30 * time.Minute)
+	if _, ok := c.GetActors(550); !ok {
+		t.Errorf("entry expired before its ttl")
+	}
+	now = now.Add(time.Hour)
+	if _, ok := c.GetActors(550); ok {
+		t.Errorf("entry still live after its ttl")
+	}
+	c.Close()
+
+	c, err = Open(path, time.Hour)
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+	if _, ok := c.GetActors(550); ok {
+		t.Errorf("expired entry loaded from log")
+	}
+}
+
+func TestCacheCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	c, err := Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	for i := 0; i < 10; i++ {
+		c.AddActors(550, set(i))
+	}
+	if err := c.Compact(); err != nil { t.Fatal(err) }
+	c.AddActors(680, set(1))
+	c.Close()
+
+	c, err = Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+	if actors, ok := c.GetActors(550); !ok || len(actors) != 1 {
+		t.Errorf("got actors %v after compaction", actors)
+	}
+	if _, ok := c.GetActors(680); !ok {
+		t.Errorf("entry added after compaction was lost")
+	}
+}
+
+func TestCacheConcurrentAdds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	c, err := Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			c.AddMovies(id, set(id, id + 1))
+			c.GetMovies(id)
+		}(i)
+	}
+	wg.Wait()
+	c.Close()
+
+	c, err = Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+	for i := 0; i < 50; i++ {
+		if movies, ok := c.GetMovies(i); !ok || len(movies) != 2 {
+			t.Errorf("got movies %v for %d", movies, i)
+		}
+	}
+}
+
+func TestBoundedCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewBounded(Limits{MaxEntries: 3})
+	c.AddMovies(1, set(10))
+	c.AddActors(2, set(20))
+	c.AddNeighbors(3, set(30))
+
+	if _, ok := c.GetMovies(1); !ok {
+		t.Fatal("entry missing before the budget was reached")
+	}
+	c.AddActors(4, set(40))
+
+	if _, ok := c.GetActors(2); ok {
+		t.Errorf("least recently used entry was not evicted")
+	}
+	if _, ok := c.GetMovies(1); !ok {
+		t.Errorf("recently used entry was evicted")
+	}
+	if _, ok := c.GetNeighbors(3); !ok {
+		t.Errorf("entry evicted early")
+	}
+
+	stats := c.Stats()
+	if stats.Entries != 3 || stats.Evictions != 1 {
+		t.Errorf("got stats %+v, wanted 3 entries and 1 eviction", stats)
+	}
+	if stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("got stats %+v, wanted 3 hits and 1 miss", stats)
+	}
+}
+
+func TestBoundedCacheByteBudget(t *testing.T) {
+	c := NewBounded(Limits{MaxBytes: 2 * entrySize(&entry{ids: set(1, 2, 3)})})
+	c.AddActors(1, set(1, 2, 3))
+	c.AddActors(2, set(1, 2, 3))
+	c.AddActors(1, set(1, 2, 3))
+	if stats := c.Stats(); stats.Entries != 2 || stats.Evictions != 0 {
+		t.Errorf("replacing an entry changed the budget: %+v", stats)
+	}
+
+	c.AddActors(3, set(1, 2, 3, 4, 5, 6))
+	stats := c.Stats()
+	if stats.Bytes > 2 * entrySize(&entry{ids: set(1, 2, 3)}) {
+		t.Errorf("cache over its byte budget: %+v", stats)
+	}
+	if _, ok := c.GetActors(3); !ok {
+		t.Errorf("newest entry was evicted")
+	}
+}
+
+func TestSetLimitsEvictsLoadedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+	c, err := Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	for i := 0; i < 10; i++ {
+		c.AddMovies(i, set(i))
+	}
+	c.Close()
+
+	c, err = Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+	c.SetLimits(Limits{MaxEntries: 4})
+	if stats := c.Stats(); stats.Entries != 4 || stats.Evictions != 6 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func TestRolesPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	c, err := Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	c.AddCast(550, map[int]Role{
+		819: {Name: "Edward Norton", Character: "The Narrator"},
+		287: {Name: "Brad Pitt", Character: "Tyler Durden"},
+	})
+	c.AddActors(680, set(1037))
+	c.Close()
+
+	c, err = Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+
+	cast, ok := c.GetCast(550)
+	if !ok || cast[287].Character != "Tyler Durden" {
+		t.Errorf("got cast %v, %v", cast, ok)
+	}
+	if actors, ok := c.GetActors(550); !ok || len(actors) != 2 {
+		t.Errorf("got actors %v, %v for a movie added with its cast", actors, ok)
+	}
+	if _, ok := c.GetCast(680); ok {
+		t.Errorf("got a cast for a movie added without roles")
+	}
+	if _, ok := c.GetFilmography(819); ok {
+		t.Errorf("got a filmography never added")
+	}
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("got %d hits and %d misses, wanted a miss for the cast added without roles",
+			stats.Hits, stats.Misses,
+		)
+	}
+	if _, ok := c.PeekCast(550); !ok {
+		t.Errorf("peek missed a cast")
+	}
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("peeking counted: %d hits and %d misses", stats.Hits, stats.Misses)
+	}
+
+	c.AddFilmography(819, map[int]Role{550: {Name: "Fight Club", Character: "The Narrator"}})
+	c.AddNeighbors(550, set(550, 10220))
+	c.DropCasts()
+	if _, ok := c.PeekCast(550); ok {
+		t.Errorf("cast survived DropCasts")
+	}
+	if _, ok := c.GetNeighbors(550); ok {
+		t.Errorf("neighbors survived DropCasts")
+	}
+	if _, ok := c.PeekFilmography(819); !ok {
+		t.Errorf("DropCasts dropped a filmography")
+	}
+	if stats := c.Stats(); stats.Entries != 1 {
+		t.Errorf("%d entries left after DropCasts, wanted 1", stats.Entries)
+	}
+}
+
+func TestCastsKeepTheirLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	c, err := Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	c.SetCastLimit(40)
+	c.AddCast(550, map[int]Role{819: {Name: "Edward Norton", Character: "The Narrator"}})
+	c.AddNeighbors(550, set(550, 10220))
+	c.AddFilmography(819, map[int]Role{550: {Name: "Fight Club", Character: "The Narrator"}})
+	c.Close()
+	// a cast logged before limits were recorded
+	old := `{"table":"movies_actors","id":680,"ids":[1037],"fetched":0}` + "\n"
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil { t.Fatal(err) }
+	file.WriteString(old)
+	file.Close()
+
+	c, err = Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	defer c.Close()
+	c.SetCastLimit(0)
+	if _, ok := c.GetCast(550); ok {
+		t.Errorf("got a cast cut to 40 for whole casts")
+	}
+	if _, ok := c.GetNeighbors(550); ok {
+		t.Errorf("got neighbors from casts cut to 40 for whole casts")
+	}
+	if _, ok := c.GetFilmography(819); !ok {
+		t.Errorf("missed a filmography, which casts limits don't cut")
+	}
+	if _, ok := c.GetActors(680); ok {
+		t.Errorf("got a cast logged without its limit")
+	}
+	c.AddCast(550, map[int]Role{
+		819: {Name: "Edward Norton", Character: "The Narrator"},
+		287: {Name: "Brad Pitt", Character: "Tyler Durden"},
+	})
+	c.Close()
+
+	c, err = Open(path, 0)
+	if err != nil { t.Fatal(err) }
+	c.SetCastLimit(0)
+	if cast, ok := c.GetCast(550); !ok || len(cast) != 2 {
+		t.Errorf("got whole cast %v, %v", cast, ok)
+	}
+	c.SetCastLimit(40)
+	if _, ok := c.GetCast(550); ok {
+		t.Errorf("got a whole cast for casts cut to 40")
+	}
+}
@@ -0,0 +1,402 @@
+package tmdbtest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//go:embed fixtures/graph.json
+var graphJSON []byte
+
+type CastMember struct {
+	Id        int    `json:"id"`
+	Character string `json:"character"`
+}
+
+type Movie struct {
+	Id          int               `json:"id"`
+	Title       string            `json:"title"`
+	ImdbId      string            `json:"imdb_id"`
+	ReleaseDate string            `json:"release_date"`
+	Popularity  float64           `json:"popularity"`
+	Titles      map[string]string `json:"titles,omitempty"`
+	Cast        []CastMember      `json:"cast"`
+}
+
+type Person struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Fixture struct {
+	Movies []Movie  `json:"movies"`
+	People []Person `json:"people"`
+}
+
+func LoadFixture() (Fixture, error) {
+	var f Fixture
+	err := json.Unmarshal(graphJSON, &f)
+	return f, err
+}
+
+// Server is a fake TMDB API serving the fixture graph over HTTP.
+type Server struct {
+	*httptest.Server
+	fixture  Fixture
+	movies   map[int]Movie
+	people   map[int]Person
+	requests atomic.Int64
+	pathsMu  sync.Mutex
+	paths    []*url.URL
+	latency  atomic.Int64
+	failMu   sync.Mutex
+	failures []failure
+	token    atomic.Value
+}
+
+type failure struct {
+	status     int
+	retryAfter string
+}
+
+func NewServer() *Server {
+	f, err := LoadFixture()
+	if err != nil { panic(err) }
+
+	s := &Server{
+		fixture: f,
+		movies:  make(map[int]Movie),
+		people:  make(map[int]Person),
+	}
+	for _, movie := range f.Movies {
+		s.movies[movie.Id] = movie
+	}
+	for _, person := range f.People {
+		s.people[person.Id] = person
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// BaseURL is the root the client should use in place of the TMDB API root.
+func (s *Server) BaseURL() string {
+	return s.URL + "/"
+}
+
+func (s *Server) Fixture() Fixture {
+	return s.fixture
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.latency.Store(int64(d))
+}
+
+// FailNext makes the next count requests fail with status, sending
+// retryAfter as the Retry-After header when it is not empty.
+func (s *Server) FailNext(count, status int, retryAfter string) {
+	s.failMu.Lock()
+	defer s.failMu.Unlock()
+	for i := 0; i < count; i++ {
+		s.failures = append(s.failures, failure{status: status, retryAfter: retryAfter})
+	}
+}
+
+// SetToken makes the server reject requests whose Authorization header isn't
+// "Bearer " + token. By default any token is accepted.
+func (s *Server) SetToken(token string) {
+	s.token.Store(token)
+}
+
+// Requests is the number of API requests the server has handled.
+func (s *Server) Requests() int {
+	return int(s.requests.Load())
+}
+
+// RequestsTo is the number of requests whose path started with prefix, as in
+// "/search/movie".
+func (s *Server) RequestsTo(prefix string) int {
+	s.pathsMu.Lock()
+	defer s.pathsMu.Unlock()
+	count := 0
+	for _, u := range s.paths {
+		if strings.HasPrefix(u.Path, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// Queries returns the decoded query strings of the requests whose path started
+// with prefix, oldest first.
+func (s *Server) Queries(prefix string) []url.Values {
+	s.pathsMu.Lock()
+	defer s.pathsMu.Unlock()
+	queries := []url.Values{}
+	for _, u := range s.paths {
+		if strings.HasPrefix(u.Path, prefix) {
+			queries = append(queries, u.Query())
+		}
+	}
+	return queries
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	s.pathsMu.Lock()
+	s.paths = append(s.paths, r.URL)
+	s.pathsMu.Unlock()
+	if latency := time.Duration(s.latency.Load()); latency > 0 {
+		time.Sleep(latency)
+	}
+	if f, ok := s.nextFailure(); ok {
+		if f.retryAfter != "" {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		writeError(w, f.status, 0, http.StatusText(f.status))
+		return
+	}
+	if !s.authorized(r.Header.Get("Authorization")) {
+		writeError(w, http.StatusUnauthorized, 7,
+			"Invalid API key: You must be granted a valid key.",
+		)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+	language := query.Get("language")
+
+	switch {
+	case len(parts) == 2 && parts[0] == "search" && parts[1] == "movie":
+		s.searchMovie(w, language, query.Get("query"), query.Get("primary_release_year"), query.Get("year"))
+	case len(parts) == 2 && parts[0] == "search" && parts[1] == "person":
+		s.searchPerson(w, query.Get("query"))
+	case len(parts) == 2 && parts[0] == "movie":
+		s.movie(w, language, parts[1])
+	case len(parts) == 2 && parts[0] == "find":
+		s.find(w, language, parts[1], query.Get("external_source"))
+	case len(parts) == 3 && parts[0] == "movie" && parts[2] == "credits":
+		s.movieCredits(w, parts[1])
+	case len(parts) == 2 && parts[0] == "person":
+		s.person(w, parts[1])
+	case len(parts) == 3 && parts[0] == "person" && parts[2] == "movie_credits":
+		s.personCredits(w, language, parts[1])
+	default:
+		writeNotFound(w)
+	}
+}
+
+func (s *Server) authorized(header string) bool {
+	if token, ok := s.token.Load().(string); ok {
+		return header == "Bearer " + token
+	}
+	return header != ""
+}
+
+func (s *Server) nextFailure() (failure, bool) {
+	s.failMu.Lock()
+	defer s.failMu.Unlock()
+	if len(s.failures) == 0 {
+		return failure{}, false
+	}
+	f := s.failures[0]
+	s.failures = s.failures[1:]
+	return f, true
+}
+
+// searchMovie matches titles in any language like search/movie, filtering on
+// the release year when primary_release_year or year is given.
+func (s *Server) searchMovie(w http.ResponseWriter, language, query, primaryYear, year string) {
+	results := []map[string]any{}
+	for _, movie := range s.fixture.Movies {
+		if primaryYear != "" && !strings.HasPrefix(movie.ReleaseDate, primaryYear + "-") {
+			continue
+		}
+		if year != "" && !strings.HasPrefix(movie.ReleaseDate, year + "-") {
+			continue
+		}
+		if matches(movie.Title, query) || matchesAny(movie.Titles, query) {
+			results = append(results, movieJSON(movie, language))
+		}
+	}
+	writeJSON(w, pageJSON(results))
+}
+
+func (s *Server) searchPerson(w http.ResponseWriter, query string) {
+	results := []map[string]any{}
+	for _, person := range s.fixture.People {
+		if matches(person.Name, query) {
+			results = append(results, personJSON(person))
+		}
+	}
+	writeJSON(w, pageJSON(results))
+}
+
+func (s *Server) movie(w http.ResponseWriter, language, id string) {
+	movie, ok := s.lookupMovie(id)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	details := movieJSON(movie, language)
+	details["imdb_id"] = movie.ImdbId
+	writeJSON(w, details)
+}
+
+// find looks movies up by IMDb id. Like TMDB it answers 200 with empty results
+// for ids it doesn't know.
+func (s *Server) find(w http.ResponseWriter, language, id, source string) {
+	if source != "imdb_id" {
+		writeError(w, http.StatusBadRequest, 5, "Invalid parameters: external_source is required.")
+		return
+	}
+	movies := []map[string]any{}
+	for _, movie := range s.fixture.Movies {
+		if movie.ImdbId == id {
+			movies = append(movies, movieJSON(movie, language))
+		}
+	}
+	writeJSON(w, map[string]any{
+		"movie_results":      movies,
+		"person_results":     []map[string]any{},
+		"tv_results":         []map[string]any{},
+		"tv_episode_results": []map[string]any{},
+		"tv_season_results":  []map[string]any{},
+	})
+}
+
+func (s *Server) movieCredits(w http.ResponseWriter, id string) {
+	movie, ok := s.lookupMovie(id)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	cast := []map[string]any{}
+	for _, member := range movie.Cast {
+		cast = append(cast, map[string]any{
+			"id":        member.Id,
+			"name":      s.people[member.Id].Name,
+			"character": member.Character,
+		})
+	}
+	writeJSON(w, map[string]any{"id": movie.Id, "cast": cast})
+}
+
+func (s *Server) person(w http.ResponseWriter, id string) {
+	person, ok := s.lookupPerson(id)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, personJSON(person))
+}
+
+func (s *Server) personCredits(w http.ResponseWriter, language, id string) {
+	person, ok := s.lookupPerson(id)
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	cast := []map[string]any{}
+	for _, movie := range s.fixture.Movies {
+		for _, member := range movie.Cast {
+			if member.Id == person.Id {
+				cast = append(cast, map[string]any{
+					"id":        movie.Id,
+					"title":     localTitle(movie, language),
+					"character": member.Character,
+				})
+			}
+		}
+	}
+	writeJSON(w, map[string]any{"id": person.Id, "cast": cast})
+}
+
+func (s *Server) lookupMovie(id string) (Movie, bool) {
+	movieId, err := strconv.Atoi(id)
+	if err != nil { return Movie{}, false }
+	movie, ok := s.movies[movieId]
+	return movie, ok
+}
+
+func (s *Server) lookupPerson(id string) (Person, bool) {
+	personId, err := strconv.Atoi(id)
+	if err != nil { return Person{}, false }
+	person, ok := s.people[personId]
+	return person, ok
+}
+
+func matches(name, query string) bool {
+	query = strings.TrimSpace(query)
+	return query != "" && strings.Contains(strings.ToLower(name), strings.ToLower(query))
+}
+
+func matchesAny(names map[string]string, query string) bool {
+	for _, name := range names {
+		if matches(name, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// localTitle is the movie's title in language, falling back to its original
+// title like TMDB does for missing translations.
+func localTitle(movie Movie, language string) string {
+	if title, ok := movie.Titles[language]; ok {
+		return title
+	}
+	return movie.Title
+}
+
+func movieJSON(movie Movie, language string) map[string]any {
+	return map[string]any{
+		"id":           movie.Id,
+		"title":        localTitle(movie, language),
+		"release_date": movie.ReleaseDate,
+		"popularity":   movie.Popularity,
+	}
+}
+
+func personJSON(person Person) map[string]any {
+	return map[string]any{"id": person.Id, "name": person.Name}
+}
+
+func pageJSON(results []map[string]any) map[string]any {
+	return map[string]any{
+		"page":          1,
+		"results":       results,
+		"total_pages":   1,
+		"total_results": len(results),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, 34,
+		"The resource you requested could not be found.",
+	)
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":        false,
+		"status_code":    code,
+		"status_message": message,
+	})
+}